@@ -2,12 +2,13 @@ package telegraf
 
 import (
 	"fmt"
-	"log"
 	"math"
 	"sync"
 	"time"
 
 	"github.com/influxdb/telegraf/internal/config"
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/internal/secret"
 
 	"github.com/influxdb/influxdb/client/v2"
 )
@@ -26,6 +27,8 @@ type Accumulator interface {
 
 	Debug() bool
 	SetDebug(enabled bool)
+
+	SetLogger(log *logger.Logger)
 }
 
 func NewAccumulator(
@@ -50,6 +53,8 @@ type accumulator struct {
 	pluginConfig *config.PluginConfig
 
 	prefix string
+
+	log *logger.Logger
 }
 
 func (ac *accumulator) Add(
@@ -69,32 +74,14 @@ func (ac *accumulator) AddFields(
 	tags map[string]string,
 	t ...time.Time,
 ) {
-	// Validate uint64 and float64 fields
-	for k, v := range fields {
-		switch val := v.(type) {
-		case uint64:
-			// InfluxDB does not support writing uint64
-			if val < uint64(9223372036854775808) {
-				fields[k] = int64(val)
-			} else {
-				fields[k] = int64(9223372036854775807)
-			}
-		case float64:
-			// NaNs are invalid values in influxdb, skip measurement
-			if math.IsNaN(val) || math.IsInf(val, 0) {
-				if ac.debug {
-					log.Printf("Measurement [%s] has a NaN or Inf field, skipping",
-						measurement)
-				}
-				return
-			}
-		}
-	}
-
 	if tags == nil {
 		tags = make(map[string]string)
 	}
 
+	if ac.pluginConfig != nil {
+		tags, fields = ac.pluginConfig.ConvertTags(tags, fields)
+	}
+
 	var timestamp time.Time
 	if len(t) > 0 {
 		timestamp = t[0]
@@ -110,6 +97,36 @@ func (ac *accumulator) AddFields(
 		if !ac.pluginConfig.ShouldPass(measurement) || !ac.pluginConfig.ShouldTagsPass(tags) {
 			return
 		}
+		// Rates are computed before uint64 counters are capped below
+		fields = ac.pluginConfig.ApplyRates(measurement, tags, fields, timestamp)
+		if len(fields) == 0 {
+			return
+		}
+	}
+
+	// Validate uint64 and float64 fields
+	for k, v := range fields {
+		switch val := v.(type) {
+		case uint64:
+			// InfluxDB does not support writing uint64
+			if val < uint64(9223372036854775808) {
+				fields[k] = int64(val)
+			} else {
+				fields[k] = int64(9223372036854775807)
+			}
+		case float64:
+			// NaNs are invalid values in influxdb, skip measurement
+			if math.IsNaN(val) || math.IsInf(val, 0) {
+				ac.log.Debugf("Measurement [%s] has a NaN or Inf field, skipping",
+					measurement)
+				return
+			}
+		}
+	}
+
+	if ac.pluginConfig != nil &&
+		!ac.pluginConfig.ShouldEmit(measurement, tags, fields, timestamp) {
+		return
 	}
 
 	for k, v := range ac.defaultTags {
@@ -120,11 +137,11 @@ func (ac *accumulator) AddFields(
 
 	pt, err := client.NewPoint(measurement, tags, fields, timestamp)
 	if err != nil {
-		log.Printf("Error adding point [%s]: %s\n", measurement, err.Error())
+		ac.log.Errorf("Error adding point [%s]: %s", measurement, err.Error())
 		return
 	}
 	if ac.debug {
-		fmt.Println("> " + pt.String())
+		fmt.Println("> " + secret.Redact(pt.String()))
 	}
 	ac.points <- pt
 }
@@ -152,3 +169,7 @@ func (ac *accumulator) Debug() bool {
 func (ac *accumulator) SetDebug(debug bool) {
 	ac.debug = debug
 }
+
+func (ac *accumulator) SetLogger(log *logger.Logger) {
+	ac.log = log
+}
@@ -3,13 +3,15 @@ package telegraf
 import (
 	"crypto/rand"
 	"fmt"
-	"log"
 	"math/big"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/influxdb/telegraf/internal"
 	"github.com/influxdb/telegraf/internal/config"
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/outputs"
 	"github.com/influxdb/telegraf/plugins"
 
@@ -19,12 +21,22 @@ import (
 // Agent runs telegraf and collects data based on the given config
 type Agent struct {
 	Config *config.Config
+
+	// Version of telegraf, reported by the /status endpoint
+	Version string
+
+	// Clock is the source of time for gathering and flushing
+	Clock internal.Clock
+
+	// pending is the number of points waiting for the next flush
+	pending int32
 }
 
 // NewAgent returns an Agent struct based off the given Config
 func NewAgent(config *config.Config) (*Agent, error) {
 	a := &Agent{
 		Config: config,
+		Clock:  internal.RealClock,
 	}
 
 	if a.Config.Agent.Hostname == "" {
@@ -41,45 +53,104 @@ func NewAgent(config *config.Config) (*Agent, error) {
 	return a, nil
 }
 
-// Connect connects to all configured outputs
+// Connect connects to all configured outputs. The members of a failover
+// group are connected by connectFailover.
 func (a *Agent) Connect() error {
 	for _, o := range a.Config.Outputs {
 		switch ot := o.Output.(type) {
 		case outputs.ServiceOutput:
 			if err := ot.Start(); err != nil {
-				log.Printf("Service for output %s failed to start, exiting\n%s\n",
-					o.Name, err.Error())
+				o.Log.Errorf("Service for output failed to start, exiting: %s",
+					err.Error())
 				return err
 			}
 		}
-
-		if a.Config.Agent.Debug {
-			log.Printf("Attempting connection to output: %s\n", o.Name)
+		if o.FailoverGroup != "" {
+			continue
 		}
+
+		o.Log.Debugf("Attempting connection to output")
 		err := o.Output.Connect()
 		if err != nil {
-			log.Printf("Failed to connect to output %s, retrying in 15s\n", o.Name)
-			time.Sleep(15 * time.Second)
+			o.Log.Warnf("Failed to connect to output, retrying in 15s: %s",
+				err.Error())
+			a.Clock.Sleep(15 * time.Second)
 			err = o.Output.Connect()
 			if err != nil {
 				return err
 			}
 		}
-		if a.Config.Agent.Debug {
-			log.Printf("Successfully connected to output: %s\n", o.Name)
+		o.Log.Debugf("Successfully connected to output")
+	}
+
+	for _, g := range a.Config.FailoverGroups() {
+		if err := a.connectFailover(g); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// connectFailover connects the members of a failover group. Members that
+// fail to connect are logged and connected again by writeFailover, so that
+// the group only fails if none of its members can connect.
+func (a *Agent) connectFailover(g *config.FailoverGroup) error {
+	var err error
+	for retry := 0; retry < 2; retry++ {
+		if retry > 0 {
+			logger.Warnf("Failover group %s: no output connected, retrying in 15s",
+				g.Name)
+			a.Clock.Sleep(15 * time.Second)
+		}
+
+		connected := false
+		for _, ro := range g.Outputs {
+			if ro.Connected() {
+				connected = true
+				continue
+			}
+			ro.Log.Debugf("Attempting connection to output")
+			if err = ro.Output.Connect(); err != nil {
+				ro.Log.Warnf("Failed to connect to output in failover group %s: %s",
+					g.Name, err.Error())
+				continue
+			}
+			ro.SetConnected(true)
+			ro.Log.Debugf("Successfully connected to output")
+			connected = true
+		}
+		if connected {
+			return nil
+		}
+	}
+	return fmt.Errorf("Failover group %s: no output could connect: %s",
+		g.Name, err)
+}
+
+// connectRoute starts and connects an output instance created for a route
+// tag value, without retrying.
+func connectRoute(o *config.RunningOutput) error {
+	switch ot := o.Output.(type) {
+	case outputs.ServiceOutput:
+		if err := ot.Start(); err != nil {
+			return err
+		}
+	}
+	o.Log.Debugf("Attempting connection to output")
+	return o.Output.Connect()
+}
+
 // Close closes the connection to all configured outputs
 func (a *Agent) Close() error {
 	var err error
 	for _, o := range a.Config.Outputs {
-		err = o.Output.Close()
-		switch ot := o.Output.(type) {
-		case outputs.ServiceOutput:
-			ot.Stop()
+		o.WaitRoutes()
+		for _, ro := range append(o.Routes(), o) {
+			err = ro.Output.Close()
+			switch ot := ro.Output.(type) {
+			case outputs.ServiceOutput:
+				ot.Stop()
+			}
 		}
 	}
 	return err
@@ -90,10 +161,10 @@ func (a *Agent) Close() error {
 func (a *Agent) gatherParallel(pointChan chan *client.Point) error {
 	var wg sync.WaitGroup
 
-	start := time.Now()
+	start := a.Clock.Now()
 	counter := 0
 	for _, plugin := range a.Config.Plugins {
-		if plugin.Config.Interval != 0 {
+		if plugin.Config.Interval != 0 || plugin.Config.Schedule != nil {
 			continue
 		}
 
@@ -104,11 +175,14 @@ func (a *Agent) gatherParallel(pointChan chan *client.Point) error {
 
 			acc := NewAccumulator(plugin.Config, pointChan)
 			acc.SetDebug(a.Config.Agent.Debug)
+			acc.SetLogger(plugin.Log)
 			acc.SetPrefix(plugin.Name + "_")
 			acc.SetDefaultTags(a.Config.Tags)
 
-			if err := plugin.Plugin.Gather(acc); err != nil {
-				log.Printf("Error in plugin [%s]: %s", plugin.Name, err)
+			err := plugin.Plugin.Gather(acc)
+			plugin.SetGatherResult(a.Clock.Now(), err)
+			if err != nil {
+				plugin.Log.Errorf("Error in plugin: %s", err)
 			}
 
 		}(plugin)
@@ -116,8 +190,8 @@ func (a *Agent) gatherParallel(pointChan chan *client.Point) error {
 
 	wg.Wait()
 
-	elapsed := time.Since(start)
-	log.Printf("Gathered metrics, (%s interval), from %d plugins in %s\n",
+	elapsed := a.Clock.Now().Sub(start)
+	logger.Debugf("Gathered metrics, (%s interval), from %d plugins in %s",
 		a.Config.Agent.Interval, counter, elapsed)
 	return nil
 }
@@ -129,35 +203,70 @@ func (a *Agent) gatherSeparate(
 	plugin *config.RunningPlugin,
 	pointChan chan *client.Point,
 ) error {
-	ticker := time.NewTicker(plugin.Config.Interval)
+	ticker := a.Clock.NewTicker(plugin.Config.Interval)
+	defer ticker.Stop()
 
 	for {
-		var outerr error
-		start := time.Now()
+		start := a.Clock.Now()
+		a.gatherPlugin(plugin, pointChan)
+		elapsed := a.Clock.Now().Sub(start)
+		plugin.Log.Debugf("Gathered metrics, (separate %s interval), in %s",
+			plugin.Config.Interval, elapsed)
 
-		acc := NewAccumulator(plugin.Config, pointChan)
-		acc.SetDebug(a.Config.Agent.Debug)
-		acc.SetPrefix(plugin.Name + "_")
-		acc.SetDefaultTags(a.Config.Tags)
-
-		if err := plugin.Plugin.Gather(acc); err != nil {
-			log.Printf("Error in plugin [%s]: %s", plugin.Name, err)
+		select {
+		case <-shutdown:
+			return nil
+		case <-ticker.C():
+			continue
 		}
+	}
+}
 
-		elapsed := time.Since(start)
-		log.Printf("Gathered metrics, (separate %s interval), from %s in %s\n",
-			plugin.Config.Interval, plugin.Name, elapsed)
-
-		if outerr != nil {
-			return outerr
+// gatherScheduled runs the plugins that have been configured with a cron
+// schedule, at the wall-clock times of the schedule.
+func (a *Agent) gatherScheduled(
+	shutdown chan struct{},
+	plugin *config.RunningPlugin,
+	pointChan chan *client.Point,
+) error {
+	schedule := plugin.Config.Schedule
+	for {
+		now := a.Clock.Now()
+		next := schedule.Next(now)
+		if next.IsZero() {
+			return fmt.Errorf("Schedule %q never runs", schedule)
 		}
+		plugin.Log.Debugf("Next gather at %s", next)
 
 		select {
 		case <-shutdown:
 			return nil
-		case <-ticker.C:
-			continue
+		case <-a.Clock.After(next.Sub(now)):
 		}
+
+		start := a.Clock.Now()
+		a.gatherPlugin(plugin, pointChan)
+		elapsed := a.Clock.Now().Sub(start)
+		plugin.Log.Debugf("Gathered metrics, (schedule %q), in %s",
+			schedule, elapsed)
+	}
+}
+
+// gatherPlugin gathers from a single plugin and records the result
+func (a *Agent) gatherPlugin(
+	plugin *config.RunningPlugin,
+	pointChan chan *client.Point,
+) {
+	acc := NewAccumulator(plugin.Config, pointChan)
+	acc.SetDebug(a.Config.Agent.Debug)
+	acc.SetLogger(plugin.Log)
+	acc.SetPrefix(plugin.Name + "_")
+	acc.SetDefaultTags(a.Config.Tags)
+
+	err := plugin.Plugin.Gather(acc)
+	plugin.SetGatherResult(a.Clock.Now(), err)
+	if err != nil {
+		plugin.Log.Errorf("Error in plugin: %s", err)
 	}
 }
 
@@ -183,23 +292,24 @@ func (a *Agent) Test() error {
 	for _, plugin := range a.Config.Plugins {
 		acc := NewAccumulator(plugin.Config, pointChan)
 		acc.SetDebug(true)
+		acc.SetLogger(plugin.Log)
 		acc.SetPrefix(plugin.Name + "_")
 
-		fmt.Printf("* Plugin: %s, Collection 1\n", plugin.Name)
+		fmt.Printf("* Plugin: %s, Collection 1\n", plugin.FullName())
 		if plugin.Config.Interval != 0 {
 			fmt.Printf("* Internal: %s\n", plugin.Config.Interval)
 		}
+		if plugin.Config.Schedule != nil {
+			fmt.Printf("* Schedule: %s\n", plugin.Config.Schedule)
+		}
 
 		if err := plugin.Plugin.Gather(acc); err != nil {
 			return err
 		}
 
-		// Special instructions for some plugins. cpu, for example, needs to be
-		// run twice in order to return cpu usage percentages.
-		switch plugin.Name {
-		case "cpu", "mongodb":
+		if needsTwoCollections(plugin.Name) {
 			time.Sleep(500 * time.Millisecond)
-			fmt.Printf("* Plugin: %s, Collection 2\n", plugin.Name)
+			fmt.Printf("* Plugin: %s, Collection 2\n", plugin.FullName())
 			if err := plugin.Plugin.Gather(acc); err != nil {
 				return err
 			}
@@ -209,47 +319,129 @@ func (a *Agent) Test() error {
 	return nil
 }
 
+// needsTwoCollections returns true for plugins that need to be gathered twice
+// before they report anything useful. cpu, for example, needs to be run twice
+// in order to return cpu usage percentages.
+func needsTwoCollections(name string) bool {
+	switch name {
+	case "cpu", "mongodb":
+		return true
+	}
+	return false
+}
+
+// Once gathers from every plugin a single time (twice for plugins that need
+// two collections), writes the result to all configured outputs and returns.
+// Outputs must already be connected. An error is returned if any plugin or
+// output failed.
+func (a *Agent) Once() error {
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+
+	points := make([]*client.Point, 0)
+	pointChan := make(chan *client.Point, 1000)
+	collected := make(chan struct{})
+	go func() {
+		for pt := range pointChan {
+			points = append(points, pt)
+		}
+		close(collected)
+	}()
+
+	failedPlugins := 0
+	for _, plugin := range a.Config.Plugins {
+		switch p := plugin.Plugin.(type) {
+		case plugins.ServicePlugin:
+			if err := p.Start(); err != nil {
+				plugin.Log.Errorf("Service for plugin failed to start: %s",
+					err.Error())
+				failedPlugins++
+				continue
+			}
+			defer p.Stop()
+		}
+
+		acc := NewAccumulator(plugin.Config, pointChan)
+		acc.SetDebug(a.Config.Agent.Debug)
+		acc.SetLogger(plugin.Log)
+		acc.SetPrefix(plugin.Name + "_")
+		acc.SetDefaultTags(a.Config.Tags)
+
+		err := plugin.Plugin.Gather(acc)
+		plugin.SetGatherResult(a.Clock.Now(), err)
+		if err != nil {
+			plugin.Log.Errorf("Error in plugin: %s", err)
+			failedPlugins++
+			continue
+		}
+
+		if needsTwoCollections(plugin.Name) {
+			time.Sleep(500 * time.Millisecond)
+			err := plugin.Plugin.Gather(acc)
+			plugin.SetGatherResult(a.Clock.Now(), err)
+			if err != nil {
+				plugin.Log.Errorf("Error in plugin: %s", err)
+				failedPlugins++
+			}
+		}
+	}
+	close(pointChan)
+	<-collected
+
+	var wg sync.WaitGroup
+	var failedOutputs int32
+	a.write(points, shutdown, true, &wg, &failedOutputs)
+	wg.Wait()
+
+	if failedPlugins > 0 || failedOutputs > 0 {
+		return fmt.Errorf("%d plugin(s) and %d output(s) failed",
+			failedPlugins, failedOutputs)
+	}
+	return nil
+}
+
 // writeOutput writes a list of points to a single output, with retries.
-// Optionally takes a `done` channel to indicate that it is done writing.
+// It returns the last write error if the points could not be written.
 func (a *Agent) writeOutput(
 	points []*client.Point,
 	ro *config.RunningOutput,
 	shutdown chan struct{},
-	wg *sync.WaitGroup,
-) {
-	defer wg.Done()
+) error {
 	if len(points) == 0 {
-		return
+		return nil
 	}
+	ro.AddBuffered(len(points))
+	defer ro.AddBuffered(-len(points))
 	retry := 0
 	retries := a.Config.Agent.FlushRetries
-	start := time.Now()
+	start := a.Clock.Now()
 
 	for {
 		err := ro.Output.Write(points)
+		ro.SetWriteResult(a.Clock.Now(), err)
 		if err == nil {
 			// Write successful
-			elapsed := time.Since(start)
-			log.Printf("Flushed %d metrics to output %s in %s\n",
-				len(points), ro.Name, elapsed)
-			return
+			elapsed := a.Clock.Now().Sub(start)
+			ro.Log.Debugf("Flushed %d metrics in %s", len(points), elapsed)
+			return nil
 		}
 
 		select {
 		case <-shutdown:
-			return
+			ro.AddDropped(len(points))
+			return err
 		default:
 			if retry >= retries {
 				// No more retries
-				msg := "FATAL: Write to output [%s] failed %d times, dropping" +
-					" %d metrics\n"
-				log.Printf(msg, ro.Name, retries+1, len(points))
-				return
+				ro.Log.Errorf("Write failed %d times, dropping %d metrics",
+					retries+1, len(points))
+				ro.AddDropped(len(points))
+				return err
 			} else if err != nil {
 				// Sleep for a retry
-				log.Printf("Error in output [%s]: %s, retrying in %s",
-					ro.Name, err.Error(), a.Config.Agent.FlushInterval.Duration)
-				time.Sleep(a.Config.Agent.FlushInterval.Duration)
+				ro.Log.Errorf("Error writing to output: %s, retrying in %s",
+					err.Error(), a.Config.Agent.FlushInterval.Duration)
+				a.Clock.Sleep(a.Config.Agent.FlushInterval.Duration)
 			}
 		}
 
@@ -264,13 +456,200 @@ func (a *Agent) flush(
 	wait bool,
 ) {
 	var wg sync.WaitGroup
+	a.write(points, shutdown, wait, &wg, nil)
+	if wait {
+		wg.Wait()
+	}
+}
+
+// write writes points to every output concurrently. Routed outputs get the
+// points of each of their instances, and failover groups are written to as
+// a whole. If wait is true, routed outputs wait for their new instances to
+// connect instead of holding their points for the next flush. If failed is
+// not nil, it is incremented for every failed write.
+func (a *Agent) write(
+	points []*client.Point,
+	shutdown chan struct{},
+	wait bool,
+	wg *sync.WaitGroup,
+	failed *int32,
+) {
+	count := func(err error) {
+		if err != nil && failed != nil {
+			atomic.AddInt32(failed, 1)
+		}
+	}
+
 	for _, o := range a.Config.Outputs {
+		if o.FailoverGroup != "" {
+			continue
+		}
+		for ro, routed := range routePoints(points, o, wait) {
+			wg.Add(1)
+			go func(ro *config.RunningOutput, routed []*client.Point) {
+				defer wg.Done()
+				count(a.writeOutput(routed, ro, shutdown))
+			}(ro, routed)
+		}
+	}
+
+	for _, g := range a.Config.FailoverGroups() {
 		wg.Add(1)
-		go a.writeOutput(points, o, shutdown, &wg)
+		go func(g *config.FailoverGroup) {
+			defer wg.Done()
+			count(a.writeFailover(points, g, shutdown))
+		}(g)
 	}
+}
+
+// writeFailover writes a list of points to the first output of a failover
+// group that accepts them, starting with the primary every time so that
+// writes go back to it once it recovers. Outputs that aren't connected are
+// connected first. The whole group is retried like a single output, and
+// its buffered and dropped points are counted against its active output.
+func (a *Agent) writeFailover(
+	points []*client.Point,
+	g *config.FailoverGroup,
+	shutdown chan struct{},
+) error {
+	if len(points) == 0 {
+		return nil
+	}
+	active := g.Active()
+	active.AddBuffered(len(points))
+	defer active.AddBuffered(-len(points))
+	retry := 0
+	retries := a.Config.Agent.FlushRetries
+	start := a.Clock.Now()
+
+	for {
+		var err error
+		for i, ro := range g.Outputs {
+			if !ro.Connected() {
+				if err = ro.Output.Connect(); err != nil {
+					ro.SetWriteResult(a.Clock.Now(), err)
+					ro.Log.Errorf("Error connecting to output in failover group %s: %s",
+						g.Name, err.Error())
+					continue
+				}
+				ro.SetConnected(true)
+				ro.Log.Infof("Connected to output in failover group %s", g.Name)
+			}
+
+			err = ro.Output.Write(points)
+			ro.SetWriteResult(a.Clock.Now(), err)
+			if err != nil {
+				ro.Log.Errorf("Error writing to output in failover group %s: %s",
+					g.Name, err.Error())
+				continue
+			}
+
+			prev := g.SetActive(i)
+			if prev != ro {
+				if i == 0 {
+					logger.Infof("Failover group %s: primary %s recovered, "+
+						"writing to it again", g.Name, ro.FullName())
+				} else {
+					logger.Warnf("Failover group %s: failing over from %s to %s",
+						g.Name, prev.FullName(), ro.FullName())
+				}
+			}
+			ro.Log.Debugf("Flushed %d metrics in %s", len(points),
+				a.Clock.Now().Sub(start))
+			return nil
+		}
+
+		select {
+		case <-shutdown:
+			g.Active().AddDropped(len(points))
+			return err
+		default:
+			if retry >= retries {
+				logger.Errorf("Failover group %s: every output failed %d times, "+
+					"dropping %d metrics", g.Name, retries+1, len(points))
+				g.Active().AddDropped(len(points))
+				return err
+			}
+			logger.Errorf("Failover group %s: every output failed, "+
+				"retrying in %s", g.Name, a.Config.Agent.FlushInterval.Duration)
+			a.Clock.Sleep(a.Config.Agent.FlushInterval.Duration)
+		}
+
+		retry++
+	}
+}
+
+// routePoints splits points between the instances of an output. Unless the
+// output has a route tag, every point goes to the output itself. Otherwise
+// points go to the instance for their tag value, and points without the tag
+// go to the output itself. Instances that are still connecting hold their
+// points, which are returned once they are connected, unless wait is true,
+// in which case routePoints waits for them to connect.
+func routePoints(
+	points []*client.Point,
+	o *config.RunningOutput,
+	wait bool,
+) map[*config.RunningOutput][]*client.Point {
+	routed := make(map[*config.RunningOutput][]*client.Point)
+	if o.RouteTag == "" {
+		routed[o] = points
+		return routed
+	}
+
+	dropped := make(map[string]int)
+	for _, pt := range points {
+		value, ok := pt.Tags()[o.RouteTag]
+		if !ok {
+			routed[o] = append(routed[o], pt)
+			continue
+		}
+		if _, ok := dropped[value]; ok {
+			dropped[value]++
+			continue
+		}
+		ro, err := o.Route(value, connectRoute)
+		if err != nil {
+			o.Log.Errorf("Failed to create output for %s=%s: %s",
+				o.RouteTag, value, err)
+			dropped[value]++
+			continue
+		}
+		routed[ro] = append(routed[ro], pt)
+	}
+	for value, n := range dropped {
+		o.Log.Errorf("Dropped %d metrics with %s=%s", n, o.RouteTag, value)
+		o.AddDropped(n)
+	}
+
 	if wait {
-		wg.Wait()
+		o.WaitRoutes()
+	}
+	// instances with held points have to be written to even if no new
+	// points were routed to them, and instances that failed to connect since
+	// they were routed to aren't in Routes anymore
+	instances := make(map[*config.RunningOutput]bool)
+	for _, ro := range o.Routes() {
+		instances[ro] = true
 	}
+	for ro := range routed {
+		if ro != o {
+			instances[ro] = true
+		}
+	}
+	for ro := range instances {
+		release, n := ro.Hold(routed[ro])
+		if n > 0 {
+			o.Log.Errorf("Dropped %d metrics of %s, which is connecting or "+
+				"failed to connect", n, ro.FullName())
+			o.AddDropped(n)
+		}
+		if len(release) > 0 {
+			routed[ro] = release
+		} else {
+			delete(routed, ro)
+		}
+	}
+	return routed
 }
 
 // flusher monitors the points input channel and flushes on the minimum interval
@@ -279,20 +658,37 @@ func (a *Agent) flusher(shutdown chan struct{}, pointChan chan *client.Point) er
 	// the flusher will flush after metrics are collected.
 	time.Sleep(time.Millisecond * 100)
 
-	ticker := time.NewTicker(a.Config.Agent.FlushInterval.Duration)
+	ticker := a.Clock.NewTicker(a.Config.Agent.FlushInterval.Duration)
+	defer ticker.Stop()
 	points := make([]*client.Point, 0)
 
 	for {
 		select {
 		case <-shutdown:
-			log.Println("Hang on, flushing any cached points before shutdown")
+			logger.Infof("Hang on, flushing any cached points before shutdown")
+			points = append(points, drain(pointChan)...)
 			a.flush(points, shutdown, true)
 			return nil
-		case <-ticker.C:
+		case <-ticker.C():
 			a.flush(points, shutdown, false)
 			points = make([]*client.Point, 0)
+			atomic.StoreInt32(&a.pending, 0)
 		case pt := <-pointChan:
 			points = append(points, pt)
+			atomic.StoreInt32(&a.pending, int32(len(points)))
+		}
+	}
+}
+
+// drain returns the points waiting in pointChan, without blocking
+func drain(pointChan chan *client.Point) []*client.Point {
+	var points []*client.Point
+	for {
+		select {
+		case pt := <-pointChan:
+			points = append(points, pt)
+		default:
+			return points
 		}
 	}
 }
@@ -311,7 +707,7 @@ func jitterInterval(ininterval, injitter time.Duration) time.Duration {
 	}
 
 	if outinterval.Nanoseconds() < time.Duration(500*time.Millisecond).Nanoseconds() {
-		log.Printf("Flush interval %s too low, setting to 500ms\n", outinterval)
+		logger.Warnf("Flush interval %s too low, setting to 500ms", outinterval)
 		outinterval = time.Duration(500 * time.Millisecond)
 	}
 
@@ -325,29 +721,43 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 	a.Config.Agent.FlushInterval.Duration = jitterInterval(a.Config.Agent.FlushInterval.Duration,
 		a.Config.Agent.FlushJitter.Duration)
 
-	log.Printf("Agent Config: Interval:%s, Debug:%#v, Hostname:%#v, "+
-		"Flush Interval:%s\n",
+	logger.Infof("Agent Config: Interval:%s, Debug:%#v, Hostname:%#v, "+
+		"Flush Interval:%s",
 		a.Config.Agent.Interval, a.Config.Agent.Debug,
 		a.Config.Agent.Hostname, a.Config.Agent.FlushInterval)
 
+	if a.Config.Agent.StatusAddress != "" {
+		if err := a.serveStatus(shutdown); err != nil {
+			return err
+		}
+	}
+
 	// channel shared between all plugin threads for accumulating points
 	pointChan := make(chan *client.Point, 1000)
 
 	// Round collection to nearest interval by sleeping
 	if a.Config.Agent.RoundInterval {
 		i := int64(a.Config.Agent.Interval.Duration)
-		time.Sleep(time.Duration(i - (time.Now().UnixNano() % i)))
+		a.Clock.Sleep(time.Duration(i - (a.Clock.Now().UnixNano() % i)))
 	}
-	ticker := time.NewTicker(a.Config.Agent.Interval.Duration)
+	ticker := a.Clock.NewTicker(a.Config.Agent.Interval.Duration)
+	defer ticker.Stop()
 
-	wg.Add(1)
+	// The flusher is shut down once gathering has stopped, so that the
+	// points of the last gathers are flushed too.
+	flushShutdown := make(chan struct{})
+	flushDone := make(chan struct{})
 	go func() {
-		defer wg.Done()
-		if err := a.flusher(shutdown, pointChan); err != nil {
-			log.Printf("Flusher routine failed, exiting: %s\n", err.Error())
+		defer close(flushDone)
+		if err := a.flusher(flushShutdown, pointChan); err != nil {
+			logger.Errorf("Flusher routine failed, exiting: %s", err.Error())
 			close(shutdown)
 		}
 	}()
+	defer func() {
+		close(flushShutdown)
+		<-flushDone
+	}()
 
 	for _, plugin := range a.Config.Plugins {
 
@@ -355,8 +765,8 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 		switch p := plugin.Plugin.(type) {
 		case plugins.ServicePlugin:
 			if err := p.Start(); err != nil {
-				log.Printf("Service for plugin %s failed to start, exiting\n%s\n",
-					plugin.Name, err.Error())
+				plugin.Log.Errorf("Service for plugin failed to start, exiting: %s",
+					err.Error())
 				return err
 			}
 			defer p.Stop()
@@ -369,7 +779,18 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 			go func(plugin *config.RunningPlugin) {
 				defer wg.Done()
 				if err := a.gatherSeparate(shutdown, plugin, pointChan); err != nil {
-					log.Printf(err.Error())
+					plugin.Log.Errorf("%s", err.Error())
+				}
+			}(plugin)
+		}
+
+		// Plugins with a cron schedule are gathered at its wall-clock times
+		if plugin.Config.Schedule != nil {
+			wg.Add(1)
+			go func(plugin *config.RunningPlugin) {
+				defer wg.Done()
+				if err := a.gatherScheduled(shutdown, plugin, pointChan); err != nil {
+					plugin.Log.Errorf("%s", err.Error())
 				}
 			}(plugin)
 		}
@@ -379,13 +800,13 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 
 	for {
 		if err := a.gatherParallel(pointChan); err != nil {
-			log.Printf(err.Error())
+			logger.Errorf("%s", err.Error())
 		}
 
 		select {
 		case <-shutdown:
 			return nil
-		case <-ticker.C:
+		case <-ticker.C():
 			continue
 		}
 	}
@@ -1,11 +1,22 @@
 package telegraf
 
 import (
-	"github.com/stretchr/testify/assert"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdb/telegraf/internal"
 	"github.com/influxdb/telegraf/internal/config"
+	"github.com/influxdb/telegraf/internal/cron"
+	"github.com/influxdb/telegraf/outputs"
+	"github.com/influxdb/telegraf/plugins/trig"
+	"github.com/influxdb/telegraf/testutil"
+
+	"github.com/influxdb/influxdb/client/v2"
 
 	// needing to load the plugins
 	_ "github.com/influxdb/telegraf/plugins/all"
@@ -154,3 +165,433 @@ func TestAgent_JitterMin(t *testing.T) {
 		}
 	}
 }
+
+type onceOutput struct {
+	err    error
+	points []*client.Point
+}
+
+func (o *onceOutput) Connect() error       { return nil }
+func (o *onceOutput) Close() error         { return nil }
+func (o *onceOutput) Description() string  { return "" }
+func (o *onceOutput) SampleConfig() string { return "" }
+func (o *onceOutput) Write(points []*client.Point) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.points = append(o.points, points...)
+	return nil
+}
+
+func newOnceAgent(output *onceOutput) *Agent {
+	c := config.NewConfig()
+	c.Agent.FlushRetries = 0
+	c.Plugins = append(c.Plugins, &config.RunningPlugin{
+		Name:   "trig",
+		Plugin: &trig.Trig{Amplitude: 10.0},
+		Config: &config.PluginConfig{Name: "trig"},
+	})
+	c.Outputs = append(c.Outputs, &config.RunningOutput{
+		Name:   "once",
+		Output: output,
+	})
+	a, _ := NewAgent(c)
+	return a
+}
+
+func TestAgent_Once(t *testing.T) {
+	output := &onceOutput{}
+	a := newOnceAgent(output)
+
+	assert.NoError(t, a.Once())
+	assert.Equal(t, 1, len(output.points))
+	assert.Equal(t, "trig_trig", output.points[0].Name())
+}
+
+func TestAgent_OnceOutputFailure(t *testing.T) {
+	output := &onceOutput{err: errors.New("connection refused")}
+	a := newOnceAgent(output)
+
+	assert.Error(t, a.Once())
+}
+
+type routeOutput struct {
+	onceOutput
+	Database string
+}
+
+func TestAgent_RoutePoints(t *testing.T) {
+	var routed []*routeOutput
+	outputs.Add("route_test", func() outputs.Output {
+		o := &routeOutput{}
+		routed = append(routed, o)
+		return o
+	})
+	defer delete(outputs.Outputs, "route_test")
+
+	c := config.NewConfig()
+	err := c.LoadConfig("./testdata/route.toml")
+	assert.NoError(t, err)
+	ro := c.Outputs[0]
+	assert.Equal(t, "tenant", ro.RouteTag)
+	assert.Equal(t, "telegraf_", ro.Output.(*routeOutput).Database)
+
+	var points []*client.Point
+	for _, tags := range []map[string]string{
+		{"tenant": "a"},
+		{"tenant": "b"},
+		{"tenant": "a"},
+		{"tenant": "c"},
+		{"host": "localhost"},
+	} {
+		pt, _ := client.NewPoint("cpu", tags,
+			map[string]interface{}{"value": 1.0}, time.Now())
+		points = append(points, pt)
+	}
+
+	// tenant c isn't in route_values, so its point is dropped
+	byOutput := routePoints(points, ro, true)
+	assert.Equal(t, 3, len(byOutput))
+	assert.Equal(t, 1, len(byOutput[ro]))
+
+	routes := ro.Routes()
+	assert.Equal(t, 2, len(routes))
+	assert.Equal(t, "tenant_a", routes[0].Alias)
+	assert.Equal(t, "route_test::tenant_b", routes[1].FullName())
+	assert.Equal(t, 2, len(byOutput[routes[0]]))
+	assert.Equal(t, 1, len(byOutput[routes[1]]))
+
+	// every tag value gets its own destination
+	assert.Equal(t, "telegraf_a", routes[0].Output.(*routeOutput).Database)
+	assert.Equal(t, "telegraf_b", routes[1].Output.(*routeOutput).Database)
+
+	// instances are reused for the same tag value
+	routePoints(points, ro, true)
+	assert.Equal(t, 2, len(ro.Routes()))
+	assert.Equal(t, 3, len(routed))
+}
+
+func TestAgent_RouteMaxInstances(t *testing.T) {
+	ro := &config.RunningOutput{
+		Name:              "once",
+		Output:            &onceOutput{},
+		RouteTag:          "tenant",
+		RouteMaxInstances: 1,
+	}
+	outputs.Add("once", func() outputs.Output { return &onceOutput{} })
+	defer delete(outputs.Outputs, "once")
+
+	var points []*client.Point
+	for _, tenant := range []string{"a", "b", "a"} {
+		pt, _ := client.NewPoint("cpu", map[string]string{"tenant": tenant},
+			map[string]interface{}{"value": 1.0}, time.Now())
+		points = append(points, pt)
+	}
+
+	byOutput := routePoints(points, ro, true)
+	assert.Equal(t, 1, len(byOutput))
+	routes := ro.Routes()
+	assert.Equal(t, 1, len(routes))
+	assert.Equal(t, "tenant_a", routes[0].Alias)
+	assert.Equal(t, 2, len(byOutput[routes[0]]))
+}
+
+// slowOutput connects once connect is closed, failing with err if it is set
+type slowOutput struct {
+	onceOutput
+	connect chan struct{}
+	err     error
+}
+
+func (o *slowOutput) Connect() error {
+	<-o.connect
+	return o.err
+}
+
+func TestAgent_RouteConnecting(t *testing.T) {
+	connect := make(chan struct{})
+	outputs.Add("slow", func() outputs.Output {
+		return &slowOutput{connect: connect}
+	})
+	defer delete(outputs.Outputs, "slow")
+	ro := &config.RunningOutput{
+		Name:     "slow",
+		Output:   &onceOutput{},
+		RouteTag: "tenant",
+	}
+
+	var points []*client.Point
+	for _, tenant := range []string{"a", "a"} {
+		pt, _ := client.NewPoint("cpu", map[string]string{"tenant": tenant},
+			map[string]interface{}{"value": 1.0}, time.Now())
+		points = append(points, pt)
+	}
+
+	// the flush doesn't wait for the new instance to connect, which holds
+	// the points until then
+	assert.Empty(t, routePoints(points, ro, false))
+	routes := ro.Routes()
+	require.Len(t, routes, 1)
+	assert.Equal(t, 2, routes[0].Status().Buffered)
+
+	close(connect)
+	ro.WaitRoutes()
+	byOutput := routePoints(nil, ro, false)
+	assert.Equal(t, 2, len(byOutput[routes[0]]))
+	assert.Equal(t, 0, routes[0].Status().Buffered)
+}
+
+func TestAgent_RouteConnectFails(t *testing.T) {
+	connect := make(chan struct{})
+	outputs.Add("slow", func() outputs.Output {
+		return &slowOutput{connect: connect, err: errors.New("unreachable")}
+	})
+	defer delete(outputs.Outputs, "slow")
+	ro := &config.RunningOutput{
+		Name:     "slow",
+		Output:   &onceOutput{},
+		RouteTag: "tenant",
+	}
+
+	pt, _ := client.NewPoint("cpu", map[string]string{"tenant": "a"},
+		map[string]interface{}{"value": 1.0}, time.Now())
+	assert.Empty(t, routePoints([]*client.Point{pt}, ro, false))
+
+	// the instance is discarded with the points it holds, and created again
+	// for the next points
+	close(connect)
+	ro.WaitRoutes()
+	assert.Empty(t, ro.Routes())
+	assert.Equal(t, 1, ro.Status().Dropped)
+	assert.Empty(t, routePoints([]*client.Point{pt}, ro, true))
+	assert.Equal(t, 2, ro.Status().Dropped)
+}
+
+func TestAgent_FailoverGroup(t *testing.T) {
+	primary := &onceOutput{err: errors.New("connection refused")}
+	standby := &onceOutput{}
+	other := &onceOutput{}
+
+	c := config.NewConfig()
+	c.Agent.FlushRetries = 0
+	c.Outputs = append(c.Outputs,
+		&config.RunningOutput{Name: "standby", Output: standby,
+			FailoverGroup: "main", FailoverPriority: 2},
+		&config.RunningOutput{Name: "primary", Output: primary,
+			FailoverGroup: "main", FailoverPriority: 1},
+		&config.RunningOutput{Name: "other", Output: other},
+	)
+	c.BuildFailoverGroups()
+	a, _ := NewAgent(c)
+
+	pt, _ := client.NewPoint("cpu", nil,
+		map[string]interface{}{"value": 1.0}, time.Now())
+	points := []*client.Point{pt}
+	shutdown := make(chan struct{})
+
+	a.flush(points, shutdown, true)
+	assert.Equal(t, 0, len(primary.points))
+	assert.Equal(t, 1, len(standby.points))
+	assert.Equal(t, 1, len(other.points))
+	groups := a.Config.FailoverGroups()
+	assert.Equal(t, 1, len(groups))
+	assert.Equal(t, "standby", groups[0].Active().Name)
+
+	// writes go back to the primary once it recovers
+	primary.err = nil
+	a.flush(points, shutdown, true)
+	assert.Equal(t, 1, len(primary.points))
+	assert.Equal(t, 1, len(standby.points))
+	assert.Equal(t, 2, len(other.points))
+	assert.Equal(t, "primary", groups[0].Active().Name)
+
+	// the group fails as a whole when every output fails
+	primary.err = errors.New("connection refused")
+	standby.err = errors.New("connection refused")
+	var wg sync.WaitGroup
+	var failed int32
+	a.write(points, shutdown, true, &wg, &failed)
+	wg.Wait()
+	assert.Equal(t, int32(1), failed)
+}
+
+// connectOutput is an output whose Connect fails with err
+type connectOutput struct {
+	onceOutput
+	err error
+}
+
+func (o *connectOutput) Connect() error { return o.err }
+
+func TestAgent_FailoverGroupConnect(t *testing.T) {
+	primary := &connectOutput{err: errors.New("connection refused")}
+	standby := &connectOutput{}
+
+	c := config.NewConfig()
+	c.Agent.FlushRetries = 0
+	c.Outputs = append(c.Outputs,
+		&config.RunningOutput{Name: "primary", Output: primary,
+			FailoverGroup: "main", FailoverPriority: 1},
+		&config.RunningOutput{Name: "standby", Output: standby,
+			FailoverGroup: "main", FailoverPriority: 2},
+	)
+	c.BuildFailoverGroups()
+	a, _ := NewAgent(c)
+
+	// the agent starts with the primary down
+	require.NoError(t, a.Connect())
+	assert.False(t, c.Outputs[0].Connected())
+	assert.True(t, c.Outputs[1].Connected())
+
+	pt, _ := client.NewPoint("cpu", nil,
+		map[string]interface{}{"value": 1.0}, time.Now())
+	points := []*client.Point{pt}
+	shutdown := make(chan struct{})
+	a.flush(points, shutdown, true)
+	assert.Equal(t, 0, len(primary.points))
+	assert.Equal(t, 1, len(standby.points))
+
+	// the primary is connected again once it is up
+	primary.err = nil
+	a.flush(points, shutdown, true)
+	assert.True(t, c.Outputs[0].Connected())
+	assert.Equal(t, 1, len(primary.points))
+	assert.Equal(t, 1, len(standby.points))
+}
+
+func TestAgent_FailoverGroupConnectFails(t *testing.T) {
+	c := config.NewConfig()
+	c.Outputs = append(c.Outputs,
+		&config.RunningOutput{Name: "primary", FailoverGroup: "main",
+			Output: &connectOutput{err: errors.New("connection refused")}},
+		&config.RunningOutput{Name: "standby", FailoverGroup: "main",
+			Output: &connectOutput{err: errors.New("connection refused")}},
+	)
+	c.BuildFailoverGroups()
+	a, _ := NewAgent(c)
+	clock := testutil.NewMockClock(time.Unix(0, 0))
+	a.Clock = clock
+
+	// the group is retried once before the agent gives up
+	done := make(chan error, 1)
+	go func() { done <- a.Connect() }()
+	require.True(t, clock.WaitForSleepers(1, time.Second))
+	clock.Add(15 * time.Second)
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Connect didn't return")
+	}
+}
+
+func newRunAgent(output *testutil.MockOutput) (*Agent, *testutil.MockClock) {
+	c := config.NewConfig()
+	c.Agent.Interval = internal.Duration{Duration: 10 * time.Second}
+	c.Agent.FlushInterval = internal.Duration{Duration: 10 * time.Second}
+	c.Agent.FlushJitter = internal.Duration{}
+	c.Agent.RoundInterval = false
+	c.Plugins = append(c.Plugins, &config.RunningPlugin{
+		Name:   "trig",
+		Plugin: &trig.Trig{Amplitude: 10.0},
+		Config: &config.PluginConfig{Name: "trig"},
+	})
+	c.Outputs = append(c.Outputs, &config.RunningOutput{
+		Name:   "mock",
+		Output: output,
+	})
+	a, _ := NewAgent(c)
+
+	clock := testutil.NewMockClock(time.Unix(0, 0))
+	a.Clock = clock
+	return a, clock
+}
+
+func TestAgent_RunFlushes(t *testing.T) {
+	output := &testutil.MockOutput{}
+	a, clock := newRunAgent(output)
+
+	err := testutil.RunIntervals(clock, 2, 10*time.Second, 3, a.Run)
+	assert.NoError(t, err)
+
+	// one gather at startup and one per interval, flushes that started before
+	// shutdown may still be writing
+	output.WaitForPoints(4, time.Second)
+	assert.Equal(t, 4, len(output.Points()))
+	for _, pt := range output.Points() {
+		assert.Equal(t, "trig_trig", pt.Name())
+	}
+}
+
+func TestAgent_RunRetries(t *testing.T) {
+	output := &testutil.MockOutput{
+		Err:        errors.New("connection refused"),
+		FailWrites: 1,
+	}
+	a, clock := newRunAgent(output)
+	a.Config.Agent.FlushRetries = 1
+
+	err := testutil.RunIntervals(clock, 2, 10*time.Second, 3, a.Run)
+	assert.NoError(t, err)
+
+	// the failed write is retried after a flush interval, the clock is moved
+	// on in case the retry started sleeping after the last interval
+	for i := 0; i < 100 && !output.WaitForPoints(4, 10*time.Millisecond); i++ {
+		clock.Add(10 * time.Second)
+	}
+	assert.Equal(t, 4, len(output.Points()))
+	output.Lock()
+	assert.Equal(t, len(output.Writes)+1, output.Attempts)
+	output.Unlock()
+}
+
+func TestAgent_RunDrops(t *testing.T) {
+	output := &testutil.MockOutput{Err: errors.New("connection refused")}
+	a, clock := newRunAgent(output)
+	a.Config.Agent.FlushRetries = 0
+
+	err := testutil.RunIntervals(clock, 2, 10*time.Second, 3, a.Run)
+	assert.NoError(t, err)
+
+	assert.True(t, output.WaitForAttempts(1, time.Second))
+	assert.Equal(t, 0, len(output.Points()))
+
+	// dropped points aren't retried
+	clock.Add(10 * time.Second)
+	assert.False(t, output.WaitForPoints(1, 10*time.Millisecond))
+}
+
+func TestAgent_RunSchedule(t *testing.T) {
+	output := &testutil.MockOutput{}
+	a, clock := newRunAgent(output)
+	schedule, err := cron.Parse("5 * * * *")
+	assert.NoError(t, err)
+	a.Config.Plugins[0].Config.Schedule = schedule
+
+	shutdown := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Run(shutdown)
+	}()
+	assert.True(t, clock.WaitForTickers(2, time.Second))
+	assert.True(t, clock.WaitForSleepers(1, time.Second))
+
+	// not gathered on the interval
+	clock.Add(4 * time.Minute)
+	assert.False(t, output.WaitForPoints(1, 10*time.Millisecond))
+
+	// gathered at :05 of every hour
+	clock.Add(time.Minute)
+	assert.True(t, clock.WaitForSleepers(1, time.Second))
+	clock.Add(10 * time.Second)
+	assert.True(t, output.WaitForPoints(1, time.Second))
+
+	clock.Add(time.Hour - 10*time.Second)
+	assert.True(t, clock.WaitForSleepers(1, time.Second))
+	clock.Add(10 * time.Second)
+	assert.True(t, output.WaitForPoints(2, time.Second))
+	assert.Equal(t, 2, len(output.Points()))
+
+	close(shutdown)
+	assert.NoError(t, <-done)
+}
@@ -10,19 +10,26 @@ import (
 
 	"github.com/influxdb/telegraf"
 	"github.com/influxdb/telegraf/internal/config"
+	"github.com/influxdb/telegraf/internal/logger"
 	_ "github.com/influxdb/telegraf/outputs/all"
 	_ "github.com/influxdb/telegraf/plugins/all"
 )
 
 var fDebug = flag.Bool("debug", false,
 	"show metrics as they're generated to stdout")
+var fQuiet = flag.Bool("quiet", false,
+	"run in quiet mode, only error messages are logged")
 var fTest = flag.Bool("test", false, "gather metrics, print them out, and exit")
+var fOnce = flag.Bool("once", false,
+	"gather metrics once, write them to the outputs, and exit")
 var fConfig = flag.String("config", "", "configuration file to load")
 var fConfigDirectory = flag.String("configdirectory", "",
 	"directory containing additional *.conf files")
 var fVersion = flag.Bool("version", false, "display the version")
 var fSampleConfig = flag.Bool("sample-config", false,
 	"print out full sample configuration")
+var fGenerated = flag.Bool("generated", false,
+	"with -sample-config, generate the plugin and output sections from their structs")
 var fPidfile = flag.String("pidfile", "", "file to write our pid to")
 var fPLuginFilters = flag.String("filter", "",
 	"filter the plugins to enable, separator is :")
@@ -30,6 +37,10 @@ var fOutputFilters = flag.String("outputfilter", "",
 	"filter the outputs to enable, separator is :")
 var fUsage = flag.String("usage", "",
 	"print usage for a plugin, ie, 'telegraf -usage mysql'")
+var fList = flag.String("list", "",
+	"list the available plugins or outputs, ie, 'telegraf -list plugins'")
+var fFormat = flag.String("format", "text",
+	"format of -list, text or json")
 
 // Telegraf version
 //	-ldflags "-X main.Version=`git describe --always --tags`"
@@ -57,14 +68,25 @@ func main() {
 	}
 
 	if *fSampleConfig {
+		if *fGenerated {
+			config.PrintGeneratedConfig(pluginFilters, outputFilters)
+			return
+		}
 		config.PrintSampleConfig(pluginFilters, outputFilters)
 		return
 	}
 
+	if *fList != "" {
+		if err := config.PrintList(os.Stdout, *fList, *fFormat); err != nil {
+			log.Fatalf("E! %s", err)
+		}
+		return
+	}
+
 	if *fUsage != "" {
 		if err := config.PrintPluginConfig(*fUsage); err != nil {
 			if err2 := config.PrintOutputConfig(*fUsage); err2 != nil {
-				log.Fatalf("%s and %s", err, err2)
+				log.Fatalf("E! %s and %s", err, err2)
 			}
 		}
 		return
@@ -81,7 +103,7 @@ func main() {
 		c.PluginFilters = pluginFilters
 		err = c.LoadConfig(*fConfig)
 		if err != nil {
-			log.Fatal(err)
+			log.Fatalf("E! %s", err)
 		}
 	} else {
 		fmt.Println("Usage: Telegraf")
@@ -92,36 +114,60 @@ func main() {
 	if *fConfigDirectory != "" {
 		err = c.LoadDirectory(*fConfigDirectory)
 		if err != nil {
-			log.Fatal(err)
+			log.Fatalf("E! %s", err)
 		}
 	}
 	if len(c.Outputs) == 0 {
-		log.Fatalf("Error: no outputs found, did you provide a valid config file?")
+		log.Fatalf("E! no outputs found, did you provide a valid config file?")
 	}
 	if len(c.Plugins) == 0 {
-		log.Fatalf("Error: no plugins found, did you provide a valid config file?")
+		log.Fatalf("E! no plugins found, did you provide a valid config file?")
 	}
 
 	ag, err := telegraf.NewAgent(c)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("E! %s", err)
 	}
+	ag.Version = Version
 
 	if *fDebug {
 		ag.Config.Agent.Debug = true
 	}
+	if *fQuiet {
+		ag.Config.Agent.Quiet = true
+	}
+
+	err = logger.Setup(logger.Config{
+		Debug:               ag.Config.Agent.Debug,
+		Quiet:               ag.Config.Agent.Quiet,
+		Logfile:             ag.Config.Agent.Logfile,
+		RotationMaxSize:     ag.Config.Agent.LogfileRotationMaxSize.Size,
+		RotationMaxArchives: ag.Config.Agent.LogfileRotationMaxArchives,
+	})
+	if err != nil {
+		log.Fatalf("E! Unable to set up logging: %s", err)
+	}
 
 	if *fTest {
 		err = ag.Test()
 		if err != nil {
-			log.Fatal(err)
+			log.Fatalf("E! %s", err)
 		}
 		return
 	}
 
 	err = ag.Connect()
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("E! %s", err)
+	}
+
+	if *fOnce {
+		err = ag.Once()
+		ag.Close()
+		if err != nil {
+			log.Fatalf("E! %s", err)
+		}
+		return
 	}
 
 	shutdown := make(chan struct{})
@@ -132,15 +178,15 @@ func main() {
 		close(shutdown)
 	}()
 
-	log.Printf("Starting Telegraf (version %s)\n", Version)
-	log.Printf("Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
-	log.Printf("Loaded plugins: %s", strings.Join(c.PluginNames(), " "))
-	log.Printf("Tags enabled: %s", c.ListTags())
+	logger.Infof("Starting Telegraf (version %s)", Version)
+	logger.Infof("Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
+	logger.Infof("Loaded plugins: %s", strings.Join(c.PluginNames(), " "))
+	logger.Infof("Tags enabled: %s", c.ListTags())
 
 	if *fPidfile != "" {
 		f, err := os.Create(*fPidfile)
 		if err != nil {
-			log.Fatalf("Unable to create pidfile: %s", err)
+			log.Fatalf("E! Unable to create pidfile: %s", err)
 		}
 
 		fmt.Fprintf(f, "%d\n", os.Getpid())
@@ -4,13 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/cron"
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/internal/secret"
 	"github.com/influxdb/telegraf/outputs"
 	"github.com/influxdb/telegraf/plugins"
 
@@ -29,6 +31,8 @@ type Config struct {
 	Agent   *AgentConfig
 	Plugins []*RunningPlugin
 	Outputs []*RunningOutput
+
+	failoverGroups []*FailoverGroup
 }
 
 func NewConfig() *Config {
@@ -40,6 +44,9 @@ func NewConfig() *Config {
 			FlushInterval: internal.Duration{10 * time.Second},
 			FlushRetries:  2,
 			FlushJitter:   internal.Duration{5 * time.Second},
+
+			LogfileRotationMaxArchives: 5,
+			HealthMaxFailedWrites:      3,
 		},
 
 		Tags:          make(map[string]string),
@@ -75,8 +82,25 @@ type AgentConfig struct {
 	Precision string
 
 	// Option for running in debug mode
-	Debug    bool
+	Debug bool
+	// Quiet only logs error messages
+	Quiet    bool
 	Hostname string
+
+	// Logfile is the file to log to, stderr is used if empty
+	Logfile string
+	// LogfileRotationMaxSize rotates the log file once it grows past this
+	// size, 0 disables rotation
+	LogfileRotationMaxSize internal.Size
+	// LogfileRotationMaxArchives is the number of rotated log files to keep
+	LogfileRotationMaxArchives int
+
+	// StatusAddress is the address of an HTTP listener serving the /health
+	// and /status endpoints, it is disabled if empty
+	StatusAddress string
+	// HealthMaxFailedWrites is the number of consecutive failed writes after
+	// which an output, and so the agent, is reported unhealthy
+	HealthMaxFailedWrites int
 }
 
 // TagFilter is the name of a tag, and the values on which to filter
@@ -87,13 +111,56 @@ type TagFilter struct {
 
 type RunningOutput struct {
 	Name   string
+	Alias  string
 	Output outputs.Output
+	Log    *logger.Logger
+
+	// RouteTag, if set, sends the points with that tag to a separate
+	// instance of the output per tag value, see Route. RouteValues limits
+	// the values that get an instance, and RouteMaxInstances the number of
+	// instances.
+	RouteTag          string
+	RouteValues       []string
+	RouteMaxInstances int
+
+	// FailoverGroup, if set, makes the output a member of a failover group,
+	// FailoverPriority orders the members, lowest first.
+	FailoverGroup    string
+	FailoverPriority int
+
+	status   outputStatus
+	route    outputRoutes
+	held     routeHeld
+	failover outputFailover
+}
+
+// FullName returns the name of the output, qualified with its alias if it
+// has one, ie "influxdb::tenant_a"
+func (ro *RunningOutput) FullName() string {
+	return fullName(ro.Name, ro.Alias)
 }
 
 type RunningPlugin struct {
 	Name   string
+	Alias  string
 	Plugin plugins.Plugin
 	Config *PluginConfig
+	Log    *logger.Logger
+
+	status pluginStatus
+}
+
+// FullName returns the name of the plugin, qualified with its alias if it
+// has one, ie "exec::mycollector"
+func (rp *RunningPlugin) FullName() string {
+	return fullName(rp.Name, rp.Alias)
+}
+
+func fullName(name, alias string) string {
+	if alias == "" {
+		return name
+	}
+	return name + "::" + alias
 }
 
 // PluginConfig containing a name, interval, and drop/pass prefix lists
@@ -108,6 +175,30 @@ type PluginConfig struct {
 	TagPass []TagFilter
 
 	Interval time.Duration
+
+	// Schedule runs the plugin at the wall-clock times of a cron expression
+	// instead of every interval.
+	Schedule *cron.Schedule
+
+	// TagsToFields are tags that are emitted as fields instead, and
+	// FieldsToTags are fields that are emitted as tags instead.
+	TagsToFields []string
+	FieldsToTags []string
+
+	// RateFields are counter fields that a per second <field>_rate is
+	// computed for, RateDropRaw drops the counters themselves.
+	// RateCounterBits is 32 for counters that wrap around at 2^32.
+	RateFields      []string
+	RateDropRaw     bool
+	RateCounterBits int
+
+	// Dedup skips points whose fields didn't change since the series was
+	// last emitted, unless DedupInterval has passed since then.
+	Dedup         bool
+	DedupInterval time.Duration
+
+	rates rateTracker
+	dedup dedupTracker
 }
 
 // ShouldPass returns true if the metric should pass, false if should drop
@@ -170,7 +261,7 @@ func (cp *PluginConfig) ShouldTagsPass(tags map[string]string) bool {
 func (c *Config) PluginNames() []string {
 	var name []string
 	for _, plugin := range c.Plugins {
-		name = append(name, plugin.Name)
+		name = append(name, plugin.FullName())
 	}
 	return name
 }
@@ -179,7 +270,7 @@ func (c *Config) PluginNames() []string {
 func (c *Config) OutputNames() []string {
 	var name []string
 	for _, output := range c.Outputs {
-		name = append(name, output.Name)
+		name = append(name, output.FullName())
 	}
 	return name
 }
@@ -241,9 +332,23 @@ var header = `# Telegraf configuration
 
   # Run telegraf in debug mode
   debug = false
+  # Run telegraf in quiet mode, only error messages are logged
+  quiet = false
   # Override default hostname, if empty use os.Hostname()
   hostname = ""
 
+  # Log to this file instead of stderr
+  # logfile = "/var/log/telegraf/telegraf.log"
+  # Rotate the log file once it grows past this size, "0MB" disables rotation
+  # logfile_rotation_max_size = "10MB"
+  # Number of rotated log files to keep
+  # logfile_rotation_max_archives = 5
+
+  # Serve /health and /status over HTTP on this address, disabled if empty
+  # status_address = ":8094"
+  # /health fails once an output has failed this many writes in a row
+  # health_max_failed_writes = 3
+
 
 ###############################################################################
 #                                  OUTPUTS                                    #
@@ -270,6 +375,21 @@ var servicePluginHeader = `
 
 // PrintSampleConfig prints the sample config
 func PrintSampleConfig(pluginFilters []string, outputFilters []string) {
+	printSampleConfig(pluginFilters, outputFilters, printConfig)
+}
+
+// PrintGeneratedConfig prints a sample config like PrintSampleConfig, with
+// the plugin and output sections generated from their structs rather than
+// hand-written.
+func PrintGeneratedConfig(pluginFilters []string, outputFilters []string) {
+	printSampleConfig(pluginFilters, outputFilters, printGeneratedConfig)
+}
+
+func printSampleConfig(
+	pluginFilters []string,
+	outputFilters []string,
+	printSection func(name string, p printer, op string),
+) {
 	fmt.Printf(header)
 
 	// Filter outputs
@@ -285,7 +405,7 @@ func PrintSampleConfig(pluginFilters []string, outputFilters []string) {
 	for _, oname := range onames {
 		creator := outputs.Outputs[oname]
 		output := creator()
-		printConfig(oname, output, "outputs")
+		printSection(oname, output, "outputs")
 	}
 
 	// Filter plugins
@@ -310,13 +430,13 @@ func PrintSampleConfig(pluginFilters []string, outputFilters []string) {
 			continue
 		}
 
-		printConfig(pname, plugin, "plugins")
+		printSection(pname, plugin, "plugins")
 	}
 
 	// Print Service Plugins
 	fmt.Printf(servicePluginHeader)
 	for name, plugin := range servPlugins {
-		printConfig(name, plugin, "plugins")
+		printSection(name, plugin, "plugins")
 	}
 }
 
@@ -335,6 +455,11 @@ func printConfig(name string, p printer, op string) {
 	}
 }
 
+func printGeneratedConfig(name string, p printer, op string) {
+	fmt.Printf("\n# %s\n[[%s.%s]]", p.Description(), op, name)
+	fmt.Print(GenerateSampleConfig(name, p, op))
+}
+
 func sliceContains(name string, list []string) bool {
 	for _, b := range list {
 		if b == name {
@@ -397,6 +522,10 @@ func (c *Config) LoadConfig(path string) error {
 		return err
 	}
 
+	if err = resolveSecrets(tbl); err != nil {
+		return err
+	}
+
 	for name, val := range tbl.Fields {
 		subTable, ok := val.(*ast.Table)
 		if !ok {
@@ -406,12 +535,12 @@ func (c *Config) LoadConfig(path string) error {
 		switch name {
 		case "agent":
 			if err = toml.UnmarshalTable(subTable, c.Agent); err != nil {
-				log.Printf("Could not parse [agent] config\n")
+				logger.Errorf("Could not parse [agent] config")
 				return err
 			}
 		case "tags":
 			if err = toml.UnmarshalTable(subTable, c.Tags); err != nil {
-				log.Printf("Could not parse [tags] config\n")
+				logger.Errorf("Could not parse [tags] config")
 				return err
 			}
 		case "outputs":
@@ -458,6 +587,49 @@ func (c *Config) LoadConfig(path string) error {
 			}
 		}
 	}
+	c.BuildFailoverGroups()
+	return nil
+}
+
+// resolveSecrets replaces the secret references, ie @{env:DB_PASS}, in every
+// string value of the table and its sub-tables with the secret itself.
+func resolveSecrets(tbl *ast.Table) error {
+	for _, val := range tbl.Fields {
+		switch v := val.(type) {
+		case *ast.Table:
+			if err := resolveSecrets(v); err != nil {
+				return err
+			}
+		case []*ast.Table:
+			for _, t := range v {
+				if err := resolveSecrets(t); err != nil {
+					return err
+				}
+			}
+		case *ast.KeyValue:
+			if err := resolveSecretValue(v.Value); err != nil {
+				return fmt.Errorf("%s: %s", v.Key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func resolveSecretValue(val ast.Value) error {
+	switch v := val.(type) {
+	case *ast.String:
+		s, err := secret.Resolve(v.Value)
+		if err != nil {
+			return err
+		}
+		v.Value = s
+	case *ast.Array:
+		for _, elem := range v.Value {
+			if err := resolveSecretValue(elem); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -471,13 +643,38 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	}
 	o := creator()
 
+	ro := &RunningOutput{
+		Name:   name,
+		Alias:  popAlias(table),
+		Output: o,
+	}
+	if err := popRoute(table, ro); err != nil {
+		return err
+	}
+	group, priority, err := popFailover(table)
+	if err != nil {
+		return err
+	}
+	if ro.RouteTag != "" && group != "" {
+		return fmt.Errorf("Output %s: route_tag can't be used in a failover group",
+			name)
+	}
+	ro.FailoverGroup = group
+	ro.FailoverPriority = priority
+	ro.route.table = table
+
+	// the output itself gets the points without the route tag, so it has no
+	// value to put in place of {{value}}
+	if ro.RouteTag != "" {
+		table = substituteTable(table, "")
+	}
 	if err := toml.UnmarshalTable(table, o); err != nil {
 		return err
 	}
 
-	ro := &RunningOutput{
-		Name:   name,
-		Output: o,
+	ro.Log = logger.New("outputs." + ro.FullName())
+	if ls, ok := o.(outputs.LoggerSetter); ok {
+		ls.SetLogger(ro.Log)
 	}
 	c.Outputs = append(c.Outputs, ro)
 	return nil
@@ -493,19 +690,40 @@ func (c *Config) addPlugin(name string, table *ast.Table) error {
 	}
 	plugin := creator()
 
+	alias := popAlias(table)
 	pluginConfig, err := applyPlugin(name, table, plugin)
 	if err != nil {
 		return err
 	}
 	rp := &RunningPlugin{
 		Name:   name,
+		Alias:  alias,
 		Plugin: plugin,
 		Config: pluginConfig,
 	}
+	rp.Log = logger.New("plugins." + rp.FullName())
+	if ls, ok := plugin.(plugins.LoggerSetter); ok {
+		ls.SetLogger(rp.Log)
+	}
 	c.Plugins = append(c.Plugins, rp)
 	return nil
 }
 
+// popAlias removes the alias option, which is valid for every plugin and
+// output, from the table and returns its value.
+func popAlias(tbl *ast.Table) string {
+	var alias string
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				alias = str.Value
+			}
+		}
+	}
+	delete(tbl.Fields, "alias")
+	return alias
+}
+
 // applyPlugin takes defined plugin names and applies them to the given
 // interface, returning a PluginConfig object in the end that can
 // be inserted into a runningPlugin by the agent.
@@ -549,6 +767,24 @@ func applyPlugin(name string, tbl *ast.Table, p plugins.Plugin) (*PluginConfig,
 		}
 	}
 
+	if node, ok := tbl.Fields["schedule"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				schedule, err := cron.Parse(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.Schedule = schedule
+			}
+		}
+	}
+
+	if cp.Interval != 0 && cp.Schedule != nil {
+		return nil, fmt.Errorf("plugin %s can't have both an interval and a "+
+			"schedule", name)
+	}
+
 	if node, ok := tbl.Fields["tagpass"]; ok {
 		if subtbl, ok := node.(*ast.Table); ok {
 			for name, val := range subtbl.Fields {
@@ -585,10 +821,99 @@ func applyPlugin(name string, tbl *ast.Table, p plugins.Plugin) (*PluginConfig,
 		}
 	}
 
+	if node, ok := tbl.Fields["tags_to_fields"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						cp.TagsToFields = append(cp.TagsToFields, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["fields_to_tags"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						cp.FieldsToTags = append(cp.FieldsToTags, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["rate_fields"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						cp.RateFields = append(cp.RateFields, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["rate_drop_raw"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				cp.RateDropRaw = b.Value == "true"
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["rate_counter_bits"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				bits, err := integer.Int()
+				if err != nil {
+					return nil, err
+				}
+				if bits != 32 && bits != 64 {
+					return nil, fmt.Errorf("rate_counter_bits must be 32 or 64, not %d",
+						bits)
+				}
+				cp.RateCounterBits = int(bits)
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["dedup"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				cp.Dedup = b.Value == "true"
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["dedup_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.DedupInterval = dur
+			}
+		}
+	}
+
 	delete(tbl.Fields, "drop")
 	delete(tbl.Fields, "pass")
 	delete(tbl.Fields, "interval")
+	delete(tbl.Fields, "schedule")
 	delete(tbl.Fields, "tagdrop")
 	delete(tbl.Fields, "tagpass")
+	delete(tbl.Fields, "tags_to_fields")
+	delete(tbl.Fields, "fields_to_tags")
+	delete(tbl.Fields, "rate_fields")
+	delete(tbl.Fields, "rate_drop_raw")
+	delete(tbl.Fields, "rate_counter_bits")
+	delete(tbl.Fields, "dedup")
+	delete(tbl.Fields, "dedup_interval")
 	return cp, toml.UnmarshalTable(tbl, p)
 }
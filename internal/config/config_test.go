@@ -1,14 +1,19 @@
 package config
 
 import (
+	"os"
 	"testing"
 	"time"
 
+	"github.com/influxdb/telegraf/outputs/influxdb"
 	"github.com/influxdb/telegraf/plugins"
 	"github.com/influxdb/telegraf/plugins/exec"
 	"github.com/influxdb/telegraf/plugins/memcached"
+	"github.com/influxdb/telegraf/plugins/mysql"
 	"github.com/influxdb/telegraf/plugins/procstat"
+	"github.com/naoina/toml"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig_LoadSinglePlugin(t *testing.T) {
@@ -108,6 +113,7 @@ func TestConfig_LoadDirectory(t *testing.T) {
 			PidFile: "/var/run/influxdb/influxd.pid",
 		},
 	}
+	pstat.SetLogger(c.Plugins[3].Log)
 
 	pConfig := &PluginConfig{Name: "procstat"}
 
@@ -116,3 +122,67 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	assert.Equal(t, pConfig, c.Plugins[3].Config,
 		"Merged Testdata did not produce correct procstat metadata.")
 }
+
+func TestConfig_LoadSecrets(t *testing.T) {
+	os.Setenv("TELEGRAF_TEST_SECRET", "envsecret")
+	defer os.Unsetenv("TELEGRAF_TEST_SECRET")
+
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/secrets.toml")
+	require.NoError(t, err)
+
+	influx := c.Outputs[0].Output.(*influxdb.InfluxDB)
+	assert.Equal(t, "envsecret", influx.Password)
+
+	my := c.Plugins[0].Plugin.(*mysql.Mysql)
+	assert.Equal(t, []string{"root:filesecret@tcp(127.0.0.1:3306)/"}, my.Servers)
+}
+
+func TestConfig_LoadSecretsMissing(t *testing.T) {
+	os.Unsetenv("TELEGRAF_TEST_SECRET")
+
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/secrets.toml")
+	assert.Error(t, err)
+}
+
+func TestConfig_LoadAlias(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/alias.toml")
+	require.NoError(t, err)
+
+	assert.Equal(t, "tenant_a", c.Outputs[0].Alias)
+	assert.Equal(t, "tenant_a", c.Outputs[0].Output.(*influxdb.InfluxDB).Database)
+	assert.Equal(t, []string{"influxdb::tenant_a"}, c.OutputNames())
+	assert.Equal(t, "outputs.influxdb::tenant_a", c.Outputs[0].Log.Name())
+
+	assert.Equal(t, "mycollector", c.Plugins[0].Alias)
+	assert.Equal(t, []string{"exec::mycollector"}, c.PluginNames())
+	assert.Equal(t, "plugins.exec::mycollector", c.Plugins[0].Log.Name())
+}
+
+func TestApplyPluginSchedule(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+servers = ["localhost"]
+schedule = "5 * * * *"
+`))
+	require.NoError(t, err)
+
+	cp, err := applyPlugin("memcached", tbl, &memcached.Memcached{})
+	require.NoError(t, err)
+	require.NotNil(t, cp.Schedule)
+	assert.Equal(t, "5 * * * *", cp.Schedule.String())
+
+	tbl, err = toml.Parse([]byte(`
+schedule = "5 * * * *"
+interval = "1h"
+`))
+	require.NoError(t, err)
+	_, err = applyPlugin("memcached", tbl, &memcached.Memcached{})
+	assert.Error(t, err)
+
+	tbl, err = toml.Parse([]byte(`schedule = "every hour"`))
+	require.NoError(t, err)
+	_, err = applyPlugin("memcached", tbl, &memcached.Memcached{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ConvertTags moves the tags listed in tags_to_fields to string fields, and
+// the fields listed in fields_to_tags to tags, converting their values to
+// strings. The maps are copied before they are changed, since plugins may
+// reuse them between points.
+func (cp *PluginConfig) ConvertTags(
+	tags map[string]string,
+	fields map[string]interface{},
+) (map[string]string, map[string]interface{}) {
+	if len(cp.TagsToFields) == 0 && len(cp.FieldsToTags) == 0 {
+		return tags, fields
+	}
+
+	newTags := make(map[string]string, len(tags))
+	for k, v := range tags {
+		newTags[k] = v
+	}
+	newFields := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		newFields[k] = v
+	}
+	tags, fields = newTags, newFields
+
+	for _, key := range cp.TagsToFields {
+		if value, ok := tags[key]; ok {
+			fields[key] = value
+			delete(tags, key)
+		}
+	}
+
+	for _, key := range cp.FieldsToTags {
+		if value, ok := fields[key]; ok {
+			tags[key] = tagValue(value)
+			delete(fields, key)
+		}
+	}
+	return tags, fields
+}
+
+func tagValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case uint64:
+		return strconv.FormatUint(val, 10)
+	case int:
+		return strconv.Itoa(val)
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	return fmt.Sprint(v)
+}
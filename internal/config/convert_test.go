@@ -0,0 +1,48 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertTags(t *testing.T) {
+	cp := &PluginConfig{
+		Name:         "procstat",
+		TagsToFields: []string{"pid"},
+		FieldsToTags: []string{"node", "port", "weight"},
+	}
+	tags := map[string]string{"pid": "1234", "exe": "nginx"}
+	fields := map[string]interface{}{
+		"cpu_usage": 1.5,
+		"node":      "db-1",
+		"port":      int64(8080),
+		"weight":    0.25,
+	}
+
+	newTags, newFields := cp.ConvertTags(tags, fields)
+	assert.Equal(t, map[string]string{
+		"exe":    "nginx",
+		"node":   "db-1",
+		"port":   "8080",
+		"weight": "0.25",
+	}, newTags)
+	assert.Equal(t, map[string]interface{}{
+		"cpu_usage": 1.5,
+		"pid":       "1234",
+	}, newFields)
+
+	// the plugin's maps are left alone
+	assert.Equal(t, "1234", tags["pid"])
+	assert.Equal(t, "db-1", fields["node"])
+}
+
+func TestConvertTagsDisabled(t *testing.T) {
+	cp := &PluginConfig{Name: "cpu"}
+	tags := map[string]string{"cpu": "cpu0"}
+	fields := map[string]interface{}{"usage_idle": 99.0}
+
+	newTags, newFields := cp.ConvertTags(tags, fields)
+	assert.Equal(t, tags, newTags)
+	assert.Equal(t, fields, newFields)
+}
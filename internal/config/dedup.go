@@ -0,0 +1,91 @@
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultDedupInterval is how often unchanged series are emitted when dedup
+// is enabled and no dedup_interval is configured.
+const DefaultDedupInterval = 10 * time.Minute
+
+// dedupEntry is the last emitted field set of a series
+type dedupEntry struct {
+	fields  map[string]interface{}
+	emitted time.Time
+}
+
+// dedupTracker keeps the last emitted field set of every series
+type dedupTracker struct {
+	sync.Mutex
+	series map[string]dedupEntry
+	swept  time.Time
+}
+
+// ShouldEmit returns false if dedup is enabled and the series was emitted
+// with the same fields less than dedup_interval ago. Otherwise the fields are
+// remembered as the last emitted ones of the series.
+func (cp *PluginConfig) ShouldEmit(
+	measurement string,
+	tags map[string]string,
+	fields map[string]interface{},
+	t time.Time,
+) bool {
+	if !cp.Dedup {
+		return true
+	}
+
+	interval := cp.DedupInterval
+	if interval <= 0 {
+		interval = DefaultDedupInterval
+	}
+
+	cp.dedup.Lock()
+	defer cp.dedup.Unlock()
+	if cp.dedup.series == nil {
+		cp.dedup.series = make(map[string]dedupEntry)
+	}
+
+	cp.dedup.expire(t, interval)
+
+	key := seriesKey(measurement, tags)
+	if last, ok := cp.dedup.series[key]; ok &&
+		t.Sub(last.emitted) < interval &&
+		sameFields(last.fields, fields) {
+		return false
+	}
+
+	copied := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		copied[k] = v
+	}
+	cp.dedup.series[key] = dedupEntry{fields: copied, emitted: t}
+	return true
+}
+
+// expire forgets the series emitted more than interval ago, which would be
+// emitted again anyway, checking at most once per interval.
+func (d *dedupTracker) expire(t time.Time, interval time.Duration) {
+	if t.Sub(d.swept) < interval {
+		return
+	}
+	d.swept = t
+	for key, entry := range d.series {
+		if t.Sub(entry.emitted) >= interval {
+			delete(d.series, key)
+		}
+	}
+}
+
+// sameFields returns true if a and b have the same keys and values
+func sameFields(a, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/plugins/memcached"
+
+	"github.com/naoina/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldEmitDedup(t *testing.T) {
+	cp := &PluginConfig{
+		Name:          "disk",
+		Dedup:         true,
+		DedupInterval: time.Minute,
+	}
+	now := time.Now()
+	tags := map[string]string{"path": "/"}
+
+	assert.True(t, cp.ShouldEmit("disk", tags,
+		map[string]interface{}{"total": int64(100)}, now))
+	assert.False(t, cp.ShouldEmit("disk", tags,
+		map[string]interface{}{"total": int64(100)}, now.Add(10*time.Second)))
+
+	// another series is tracked separately
+	assert.True(t, cp.ShouldEmit("disk", map[string]string{"path": "/home"},
+		map[string]interface{}{"total": int64(100)}, now.Add(10*time.Second)))
+
+	// changed fields are emitted right away
+	assert.True(t, cp.ShouldEmit("disk", tags,
+		map[string]interface{}{"total": int64(200)}, now.Add(20*time.Second)))
+	assert.False(t, cp.ShouldEmit("disk", tags,
+		map[string]interface{}{"total": int64(200)}, now.Add(30*time.Second)))
+
+	// unchanged fields are emitted again once dedup_interval has passed
+	assert.True(t, cp.ShouldEmit("disk", tags,
+		map[string]interface{}{"total": int64(200)}, now.Add(80*time.Second)))
+}
+
+func TestShouldEmitExpiry(t *testing.T) {
+	cp := &PluginConfig{
+		Name:          "disk",
+		Dedup:         true,
+		DedupInterval: time.Minute,
+	}
+	now := time.Now()
+	fields := map[string]interface{}{"total": int64(100)}
+
+	assert.True(t, cp.ShouldEmit("disk", map[string]string{"path": "/"},
+		fields, now))
+	assert.True(t, cp.ShouldEmit("disk", map[string]string{"path": "/home"},
+		fields, now.Add(30*time.Second)))
+	assert.Equal(t, 2, len(cp.dedup.series))
+
+	// "/" goes away and is forgotten once dedup_interval has passed
+	assert.False(t, cp.ShouldEmit("disk", map[string]string{"path": "/home"},
+		fields, now.Add(70*time.Second)))
+	assert.Equal(t, 1, len(cp.dedup.series))
+
+	// a field with the same value but another type is a change
+	assert.True(t, cp.ShouldEmit("disk", map[string]string{"path": "/home"},
+		map[string]interface{}{"total": float64(100)}, now.Add(80*time.Second)))
+}
+
+func TestShouldEmitDisabled(t *testing.T) {
+	cp := &PluginConfig{Name: "mem"}
+	now := time.Now()
+	fields := map[string]interface{}{"total": int64(100)}
+
+	assert.True(t, cp.ShouldEmit("mem", nil, fields, now))
+	assert.True(t, cp.ShouldEmit("mem", nil, fields, now))
+}
+
+func TestApplyPluginRateAndDedup(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+servers = ["localhost"]
+rate_fields = ["cmd_get", "cmd_set"]
+rate_drop_raw = true
+rate_counter_bits = 32
+dedup = true
+dedup_interval = "1h"
+`))
+	require.NoError(t, err)
+
+	m := &memcached.Memcached{}
+	cp, err := applyPlugin("memcached", tbl, m)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"localhost"}, m.Servers)
+	assert.Equal(t, []string{"cmd_get", "cmd_set"}, cp.RateFields)
+	assert.True(t, cp.RateDropRaw)
+	assert.Equal(t, 32, cp.RateCounterBits)
+	assert.True(t, cp.Dedup)
+	assert.Equal(t, time.Hour, cp.DedupInterval)
+
+	tbl, err = toml.Parse([]byte(`rate_counter_bits = 16`))
+	require.NoError(t, err)
+	_, err = applyPlugin("memcached", tbl, &memcached.Memcached{})
+	assert.Error(t, err)
+}
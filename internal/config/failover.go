@@ -0,0 +1,125 @@
+package config
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/naoina/toml/ast"
+)
+
+// FailoverGroup is a set of outputs that points are written to one at a
+// time, in order of failover_priority. The first output that accepts a write
+// becomes the active one.
+type FailoverGroup struct {
+	Name    string
+	Outputs []*RunningOutput
+
+	mu     sync.Mutex
+	active int
+}
+
+// Active returns the output that the last successful write went to
+func (g *FailoverGroup) Active() *RunningOutput {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.Outputs[g.active]
+}
+
+// SetActive records that the last successful write went to Outputs[i], and
+// returns the output that was active before.
+func (g *FailoverGroup) SetActive(i int) *RunningOutput {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	prev := g.Outputs[g.active]
+	g.active = i
+	return prev
+}
+
+// outputFailover tracks whether a failover group member is connected. Members
+// that couldn't connect are left to the group to connect again.
+type outputFailover struct {
+	sync.Mutex
+	connected bool
+}
+
+// SetConnected records whether the output is connected
+func (ro *RunningOutput) SetConnected(connected bool) {
+	ro.failover.Lock()
+	defer ro.failover.Unlock()
+	ro.failover.connected = connected
+}
+
+// Connected returns true once the output is connected
+func (ro *RunningOutput) Connected() bool {
+	ro.failover.Lock()
+	defer ro.failover.Unlock()
+	return ro.failover.connected
+}
+
+type byPriority []*RunningOutput
+
+func (s byPriority) Len() int      { return len(s) }
+func (s byPriority) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byPriority) Less(i, j int) bool {
+	return s[i].FailoverPriority < s[j].FailoverPriority
+}
+
+// BuildFailoverGroups groups the configured outputs by failover_group,
+// ordered by failover_priority. It is called once the config is loaded; the
+// groups and their outputs must not change afterwards.
+func (c *Config) BuildFailoverGroups() {
+	members := make(map[string][]*RunningOutput)
+	var names []string
+	for _, ro := range c.Outputs {
+		if ro.FailoverGroup == "" {
+			continue
+		}
+		if _, ok := members[ro.FailoverGroup]; !ok {
+			names = append(names, ro.FailoverGroup)
+		}
+		members[ro.FailoverGroup] = append(members[ro.FailoverGroup], ro)
+	}
+	sort.Strings(names)
+
+	groups := make([]*FailoverGroup, 0, len(names))
+	for _, name := range names {
+		outputs := members[name]
+		sort.Stable(byPriority(outputs))
+		groups = append(groups, &FailoverGroup{Name: name, Outputs: outputs})
+	}
+	c.failoverGroups = groups
+}
+
+// FailoverGroups returns the failover groups built by BuildFailoverGroups,
+// ordered by name
+func (c *Config) FailoverGroups() []*FailoverGroup {
+	return c.failoverGroups
+}
+
+// popFailover removes the failover_group and failover_priority options from
+// an output table
+func popFailover(tbl *ast.Table) (string, int, error) {
+	var group string
+	var priority int
+	if node, ok := tbl.Fields["failover_group"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				group = str.Value
+			}
+		}
+	}
+	if node, ok := tbl.Fields["failover_priority"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				p, err := integer.Int()
+				if err != nil {
+					return "", 0, err
+				}
+				priority = int(p)
+			}
+		}
+	}
+	delete(tbl.Fields, "failover_group")
+	delete(tbl.Fields, "failover_priority")
+	return group, priority, nil
+}
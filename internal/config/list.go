@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/influxdb/telegraf/outputs"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+// Registered describes a plugin or output that is compiled in
+type Registered struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Service     bool   `json:"service"`
+}
+
+// ListPlugins returns the registered plugins, ordered by name
+func ListPlugins() []Registered {
+	list := make([]Registered, 0, len(plugins.Plugins))
+	for name, creator := range plugins.Plugins {
+		p := creator()
+		_, service := p.(plugins.ServicePlugin)
+		list = append(list, Registered{
+			Name:        name,
+			Description: p.Description(),
+			Service:     service,
+		})
+	}
+	sort.Sort(byName(list))
+	return list
+}
+
+// ListOutputs returns the registered outputs, ordered by name
+func ListOutputs() []Registered {
+	list := make([]Registered, 0, len(outputs.Outputs))
+	for name, creator := range outputs.Outputs {
+		o := creator()
+		_, service := o.(outputs.ServiceOutput)
+		list = append(list, Registered{
+			Name:        name,
+			Description: o.Description(),
+			Service:     service,
+		})
+	}
+	sort.Sort(byName(list))
+	return list
+}
+
+type byName []Registered
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+// PrintList writes the registered plugins or outputs, depending on what, to
+// w. format is "text", a table of names and descriptions, or "json".
+func PrintList(w io.Writer, what string, format string) error {
+	var list []Registered
+	switch what {
+	case "plugins":
+		list = ListPlugins()
+	case "outputs":
+		list = ListOutputs()
+	default:
+		return fmt.Errorf("Cannot list %q, must be plugins or outputs", what)
+	}
+
+	switch format {
+	case "json":
+		buf, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", buf)
+		return err
+	case "text", "":
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		for _, r := range list {
+			service := ""
+			if r.Service {
+				service = "service"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Name, service, r.Description)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("Unknown list format %q, must be text or json", format)
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintList_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, PrintList(&buf, "plugins", "json"))
+
+	var list []Registered
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &list))
+	found := make(map[string]Registered)
+	for _, r := range list {
+		found[r.Name] = r
+	}
+
+	assert.False(t, found["memcached"].Service)
+	assert.NotEmpty(t, found["memcached"].Description)
+	assert.True(t, found["statsd"].Service)
+	assert.True(t, found["execd"].Service)
+}
+
+func TestPrintList_Text(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, PrintList(&buf, "outputs", "text"))
+
+	outputs := ListOutputs()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Equal(t, len(outputs), len(lines))
+	for i, line := range lines {
+		assert.True(t, strings.HasPrefix(line, outputs[i].Name+" "), line)
+		assert.True(t, strings.HasSuffix(line, outputs[i].Description), line)
+	}
+}
+
+func TestPrintList_Errors(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Error(t, PrintList(&buf, "inputs", "text"))
+	assert.Error(t, PrintList(&buf, "plugins", "yaml"))
+}
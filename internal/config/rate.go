@@ -0,0 +1,189 @@
+package config
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateExpiryIntervals is how many gather intervals a series can go unseen
+// before its samples are forgotten.
+const rateExpiryIntervals = 5
+
+// counterSample is the last value of a counter field of a series
+type counterSample struct {
+	integer bool
+	ivalue  uint64
+	fvalue  float64
+	t       time.Time
+}
+
+// rateSeries are the last samples of the rate fields of a series
+type rateSeries struct {
+	seen     time.Time
+	counters map[string]counterSample
+}
+
+// rateTracker keeps the last sample of every rate field, per series. The
+// gather interval isn't known here, so it is taken to be the longest time
+// seen between two samples of a series.
+type rateTracker struct {
+	sync.Mutex
+	series   map[string]*rateSeries
+	interval time.Duration
+	swept    time.Time
+}
+
+// ApplyRates adds a <field>_rate field, in units per second, for every field
+// listed in rate_fields, based on the previous value of that field in the
+// same series. Nothing is emitted on the first sample of a series or when
+// the counter was reset. A counter that went down from the upper half of the
+// 64 bit range, or of the 32 bit range if rate_counter_bits is 32, is assumed
+// to have wrapped around. If rate_drop_raw is set the counter fields
+// themselves are removed. The fields are copied before they are changed,
+// since plugins may reuse them between points.
+func (cp *PluginConfig) ApplyRates(
+	measurement string,
+	tags map[string]string,
+	fields map[string]interface{},
+	t time.Time,
+) map[string]interface{} {
+	if len(cp.RateFields) == 0 {
+		return fields
+	}
+
+	newFields := make(map[string]interface{}, len(fields)+len(cp.RateFields))
+	for k, v := range fields {
+		newFields[k] = v
+	}
+	fields = newFields
+
+	cp.rates.Lock()
+	defer cp.rates.Unlock()
+	if cp.rates.series == nil {
+		cp.rates.series = make(map[string]*rateSeries)
+	}
+	cp.rates.expire(t)
+
+	key := seriesKey(measurement, tags)
+	series, ok := cp.rates.series[key]
+	if !ok {
+		series = &rateSeries{counters: make(map[string]counterSample)}
+		cp.rates.series[key] = series
+	} else if gap := t.Sub(series.seen); gap > cp.rates.interval {
+		cp.rates.interval = gap
+	}
+	series.seen = t
+
+	for _, name := range cp.RateFields {
+		v, ok := fields[name]
+		if !ok {
+			continue
+		}
+		sample, ok := newCounterSample(v, t)
+		if !ok {
+			continue
+		}
+		if cp.RateDropRaw {
+			delete(fields, name)
+		}
+
+		prev, ok := series.counters[name]
+		series.counters[name] = sample
+		if !ok {
+			continue
+		}
+		elapsed := sample.t.Sub(prev.t).Seconds()
+		if elapsed <= 0 {
+			continue
+		}
+		if delta, ok := counterDelta(prev, sample, cp.RateCounterBits); ok {
+			fields[name+"_rate"] = delta / elapsed
+		}
+	}
+	return fields
+}
+
+// expire forgets the series that weren't seen for rateExpiryIntervals
+// intervals, checking at most once per interval.
+func (r *rateTracker) expire(t time.Time) {
+	if r.interval <= 0 || t.Sub(r.swept) < r.interval {
+		return
+	}
+	r.swept = t
+	for key, series := range r.series {
+		if t.Sub(series.seen) > rateExpiryIntervals*r.interval {
+			delete(r.series, key)
+		}
+	}
+}
+
+func newCounterSample(v interface{}, t time.Time) (counterSample, bool) {
+	switch val := v.(type) {
+	case uint64:
+		return counterSample{integer: true, ivalue: val, t: t}, true
+	case int64:
+		if val >= 0 {
+			return counterSample{integer: true, ivalue: uint64(val), t: t}, true
+		}
+		return counterSample{fvalue: float64(val), t: t}, true
+	case int:
+		if val >= 0 {
+			return counterSample{integer: true, ivalue: uint64(val), t: t}, true
+		}
+		return counterSample{fvalue: float64(val), t: t}, true
+	case float64:
+		return counterSample{fvalue: val, t: t}, true
+	}
+	return counterSample{}, false
+}
+
+// counterDelta returns how much the counter increased between prev and cur,
+// or false if it was reset. bits is the width of the counter, 32 or 64.
+func counterDelta(prev, cur counterSample, bits int) (float64, bool) {
+	if !prev.integer || !cur.integer {
+		if cur.value() < prev.value() {
+			return 0, false
+		}
+		return cur.value() - prev.value(), true
+	}
+
+	if cur.ivalue >= prev.ivalue {
+		return float64(cur.ivalue - prev.ivalue), true
+	}
+	if bits == 32 {
+		if prev.ivalue >= 1<<31 && prev.ivalue < 1<<32 {
+			return float64(1<<32 - prev.ivalue + cur.ivalue), true
+		}
+		return 0, false
+	}
+	if prev.ivalue >= 1<<63 {
+		// wrapped around 2^64, uint64 arithmetic does the rest
+		return float64(cur.ivalue - prev.ivalue), true
+	}
+	return 0, false
+}
+
+func (s counterSample) value() float64 {
+	if s.integer {
+		return float64(s.ivalue)
+	}
+	return s.fvalue
+}
+
+// seriesKey identifies a series by its measurement and tag set
+func seriesKey(measurement string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(tags)+1)
+	parts = append(parts, measurement)
+	for _, k := range keys {
+		parts = append(parts, k+"="+tags[k])
+	}
+	return strings.Join(parts, ",")
+}
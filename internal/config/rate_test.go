@@ -0,0 +1,140 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyRates(t *testing.T) {
+	cp := &PluginConfig{Name: "net", RateFields: []string{"bytes_sent"}}
+	now := time.Now()
+	tags := map[string]string{"interface": "eth0"}
+
+	fields := map[string]interface{}{"bytes_sent": uint64(1000), "drops": 1}
+	fields = cp.ApplyRates("net", tags, fields, now)
+	_, ok := fields["bytes_sent_rate"]
+	assert.False(t, ok)
+
+	fields = map[string]interface{}{"bytes_sent": uint64(3000), "drops": 1}
+	fields = cp.ApplyRates("net", tags, fields, now.Add(10*time.Second))
+	assert.Equal(t, float64(200), fields["bytes_sent_rate"])
+	assert.Equal(t, uint64(3000), fields["bytes_sent"])
+	_, ok = fields["drops_rate"]
+	assert.False(t, ok)
+
+	// another series has its own baseline
+	fields = map[string]interface{}{"bytes_sent": uint64(5000)}
+	fields = cp.ApplyRates("net", map[string]string{"interface": "eth1"},
+		fields, now.Add(10*time.Second))
+	_, ok = fields["bytes_sent_rate"]
+	assert.False(t, ok)
+}
+
+func TestApplyRatesCopiesFields(t *testing.T) {
+	cp := &PluginConfig{
+		Name:        "net",
+		RateFields:  []string{"bytes_sent"},
+		RateDropRaw: true,
+	}
+	now := time.Now()
+
+	fields := map[string]interface{}{"bytes_sent": uint64(1000)}
+	cp.ApplyRates("net", nil, fields, now)
+	fields = map[string]interface{}{"bytes_sent": uint64(2000)}
+	cp.ApplyRates("net", nil, fields, now.Add(time.Second))
+	assert.Equal(t, map[string]interface{}{"bytes_sent": uint64(2000)}, fields)
+}
+
+func TestApplyRatesReset(t *testing.T) {
+	cp := &PluginConfig{Name: "redis", RateFields: []string{"total_commands_processed"}}
+	now := time.Now()
+
+	cp.ApplyRates("redis", nil,
+		map[string]interface{}{"total_commands_processed": int64(50000)}, now)
+
+	fields := map[string]interface{}{"total_commands_processed": int64(100)}
+	fields = cp.ApplyRates("redis", nil, fields, now.Add(time.Second))
+	_, ok := fields["total_commands_processed_rate"]
+	assert.False(t, ok)
+
+	fields = map[string]interface{}{"total_commands_processed": int64(600)}
+	fields = cp.ApplyRates("redis", nil, fields, now.Add(2*time.Second))
+	assert.Equal(t, float64(500), fields["total_commands_processed_rate"])
+
+	// a 64 bit counter that is reset from above 2^31 isn't taken for a 32
+	// bit wrap around
+	cp.ApplyRates("redis", nil,
+		map[string]interface{}{"total_commands_processed": int64(3000000000)},
+		now.Add(3*time.Second))
+	fields = map[string]interface{}{"total_commands_processed": int64(100)}
+	fields = cp.ApplyRates("redis", nil, fields, now.Add(4*time.Second))
+	_, ok = fields["total_commands_processed_rate"]
+	assert.False(t, ok)
+}
+
+func TestApplyRatesWraparound(t *testing.T) {
+	cp := &PluginConfig{Name: "io", RateFields: []string{"c64"}}
+	now := time.Now()
+
+	cp.ApplyRates("io", nil,
+		map[string]interface{}{"c64": uint64(1<<64 - 100)}, now)
+	fields := map[string]interface{}{"c64": uint64(300)}
+	fields = cp.ApplyRates("io", nil, fields, now.Add(2*time.Second))
+	assert.Equal(t, float64(200), fields["c64_rate"])
+
+	cp = &PluginConfig{
+		Name:            "snmp",
+		RateFields:      []string{"c32"},
+		RateCounterBits: 32,
+	}
+	cp.ApplyRates("snmp", nil,
+		map[string]interface{}{"c32": uint64(1<<32 - 100)}, now)
+	fields = map[string]interface{}{"c32": uint64(100)}
+	fields = cp.ApplyRates("snmp", nil, fields, now.Add(2*time.Second))
+	assert.Equal(t, float64(100), fields["c32_rate"])
+}
+
+func TestApplyRatesExpiry(t *testing.T) {
+	cp := &PluginConfig{Name: "net", RateFields: []string{"bytes_sent"}}
+	now := time.Now()
+	eth0 := map[string]string{"interface": "eth0"}
+	eth1 := map[string]string{"interface": "eth1"}
+
+	for i := 0; i < 2; i++ {
+		cp.ApplyRates("net", eth0, map[string]interface{}{"bytes_sent": 1000},
+			now.Add(time.Duration(i)*10*time.Second))
+	}
+	cp.ApplyRates("net", eth1, map[string]interface{}{"bytes_sent": 1000}, now)
+	assert.Equal(t, 2, len(cp.rates.series))
+
+	// eth1 goes away, and is forgotten after 5 intervals
+	for i := 2; i < 8; i++ {
+		cp.ApplyRates("net", eth0, map[string]interface{}{"bytes_sent": 1000},
+			now.Add(time.Duration(i)*10*time.Second))
+	}
+	assert.Equal(t, 1, len(cp.rates.series))
+	_, ok := cp.rates.series[seriesKey("net", eth0)]
+	assert.True(t, ok)
+}
+
+func TestApplyRatesDropRaw(t *testing.T) {
+	cp := &PluginConfig{
+		Name:        "mysql",
+		RateFields:  []string{"queries"},
+		RateDropRaw: true,
+	}
+	now := time.Now()
+
+	fields := map[string]interface{}{"queries": 10.0, "threads": 4}
+	fields = cp.ApplyRates("mysql", nil, fields, now)
+	assert.Equal(t, map[string]interface{}{"threads": 4}, fields)
+
+	fields = map[string]interface{}{"queries": 40.0, "threads": 4}
+	fields = cp.ApplyRates("mysql", nil, fields, now.Add(3*time.Second))
+	assert.Equal(t, map[string]interface{}{
+		"queries_rate": float64(10),
+		"threads":      4,
+	}, fields)
+}
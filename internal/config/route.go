@@ -0,0 +1,296 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/outputs"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/naoina/toml"
+	"github.com/naoina/toml/ast"
+)
+
+// DefaultRouteMaxInstances is the number of instances a routed output
+// creates at most, unless route_max_instances is set.
+const DefaultRouteMaxInstances = 100
+
+// maxHeldPoints is the number of points an output instance holds at most
+// while it is connecting
+const maxHeldPoints = 10000
+
+// routeValuePlaceholder is replaced with the route tag value in the string
+// options of an output instance.
+const routeValuePlaceholder = "{{value}}"
+
+// outputRoutes are the instances of a routed output, one per value of its
+// route tag, all created from the same output table.
+type outputRoutes struct {
+	sync.Mutex
+	table      *ast.Table
+	instances  map[string]*RunningOutput
+	connecting sync.WaitGroup
+}
+
+// routeHeld are the points of an output instance that is still connecting.
+// An instance whose connection failed drops its points instead.
+type routeHeld struct {
+	sync.Mutex
+	connecting bool
+	failed     bool
+	points     []*client.Point
+}
+
+// Route returns the output instance for points whose route tag has the given
+// value. The instance is created from the output's config the first time a
+// value is seen, with {{value}} replaced by the tag value in its string
+// options and an alias of <tag>_<value>. connect is called on it in the
+// background, so that a slow connection doesn't hold up the flush, and the
+// instance holds its points until then, see Hold. If connect fails the
+// instance is discarded, and will be created again next time. Values that
+// aren't in RouteValues, when it is set, and new values once
+// RouteMaxInstances instances exist are refused.
+func (ro *RunningOutput) Route(
+	value string,
+	connect func(*RunningOutput) error,
+) (*RunningOutput, error) {
+	ro.route.Lock()
+	defer ro.route.Unlock()
+	if instance, ok := ro.route.instances[value]; ok {
+		return instance, nil
+	}
+
+	if len(ro.RouteValues) > 0 && !sliceContains(value, ro.RouteValues) {
+		return nil, fmt.Errorf("%s is not in route_values", value)
+	}
+	if ro.RouteMaxInstances > 0 && len(ro.route.instances) >= ro.RouteMaxInstances {
+		return nil, fmt.Errorf("route_max_instances (%d) reached",
+			ro.RouteMaxInstances)
+	}
+
+	instance, err := ro.newRoute(value)
+	if err != nil {
+		return nil, err
+	}
+	instance.held.connecting = true
+	if ro.route.instances == nil {
+		ro.route.instances = make(map[string]*RunningOutput)
+	}
+	ro.route.instances[value] = instance
+
+	ro.route.connecting.Add(1)
+	go func() {
+		defer ro.route.connecting.Done()
+		ro.connectRoute(value, instance, connect)
+	}()
+	return instance, nil
+}
+
+// connectRoute connects an output instance created by Route. If it fails,
+// the instance is discarded along with the points it holds.
+func (ro *RunningOutput) connectRoute(
+	value string,
+	instance *RunningOutput,
+	connect func(*RunningOutput) error,
+) {
+	err := connect(instance)
+
+	instance.held.Lock()
+	instance.held.connecting = false
+	dropped := 0
+	if err != nil {
+		instance.held.failed = true
+		dropped = len(instance.held.points)
+		instance.held.points = nil
+	}
+	instance.held.Unlock()
+	if err == nil {
+		return
+	}
+
+	ro.route.Lock()
+	if ro.route.instances[value] == instance {
+		delete(ro.route.instances, value)
+	}
+	ro.route.Unlock()
+
+	instance.AddBuffered(-dropped)
+	ro.Log.Errorf("Failed to connect output for %s=%s: %s, dropping %d metrics",
+		ro.RouteTag, value, err, dropped)
+	ro.AddDropped(dropped)
+}
+
+// WaitRoutes waits for the output instances that are connecting to be
+// connected, or discarded if they can't be.
+func (ro *RunningOutput) WaitRoutes() {
+	ro.route.connecting.Wait()
+}
+
+// Hold holds points while an output instance created by Route is
+// connecting, up to maxHeldPoints, and returns nil. Once the instance is
+// connected, it returns the points held so far followed by points, to be
+// written. It also returns the number of points that were dropped, because
+// the instance holds too many or its connection failed.
+func (ro *RunningOutput) Hold(
+	points []*client.Point,
+) (release []*client.Point, dropped int) {
+	ro.held.Lock()
+	defer ro.held.Unlock()
+
+	if ro.held.failed {
+		return nil, len(points)
+	}
+	if ro.held.connecting {
+		if room := maxHeldPoints - len(ro.held.points); len(points) > room {
+			dropped = len(points) - room
+			points = points[:room]
+		}
+		ro.held.points = append(ro.held.points, points...)
+		ro.AddBuffered(len(points))
+		return nil, dropped
+	}
+
+	if len(ro.held.points) > 0 {
+		ro.AddBuffered(-len(ro.held.points))
+		points = append(ro.held.points, points...)
+		ro.held.points = nil
+	}
+	return points, 0
+}
+
+// newRoute creates the output instance for a route tag value
+func (ro *RunningOutput) newRoute(value string) (*RunningOutput, error) {
+	creator, ok := outputs.Outputs[ro.Name]
+	if !ok {
+		return nil, fmt.Errorf("Undefined but requested output: %s", ro.Name)
+	}
+	o := creator()
+	if ro.route.table != nil {
+		table := substituteTable(ro.route.table, value)
+		if err := toml.UnmarshalTable(table, o); err != nil {
+			return nil, err
+		}
+	}
+
+	alias := ro.RouteTag + "_" + value
+	if ro.Alias != "" {
+		alias = ro.Alias + "_" + alias
+	}
+	instance := &RunningOutput{
+		Name:   ro.Name,
+		Alias:  alias,
+		Output: o,
+	}
+	instance.Log = logger.New("outputs." + instance.FullName())
+	if ls, ok := o.(outputs.LoggerSetter); ok {
+		ls.SetLogger(instance.Log)
+	}
+	return instance, nil
+}
+
+// substituteTable returns a copy of an output table with {{value}} replaced
+// by value in every string, including those in arrays and sub-tables.
+func substituteTable(tbl *ast.Table, value string) *ast.Table {
+	cp := *tbl
+	cp.Fields = make(map[string]interface{}, len(tbl.Fields))
+	for name, node := range tbl.Fields {
+		switch n := node.(type) {
+		case *ast.KeyValue:
+			kv := *n
+			kv.Value = substituteValue(n.Value, value)
+			cp.Fields[name] = &kv
+		case *ast.Table:
+			cp.Fields[name] = substituteTable(n, value)
+		case []*ast.Table:
+			tables := make([]*ast.Table, len(n))
+			for i, t := range n {
+				tables[i] = substituteTable(t, value)
+			}
+			cp.Fields[name] = tables
+		default:
+			cp.Fields[name] = node
+		}
+	}
+	return &cp
+}
+
+func substituteValue(val ast.Value, value string) ast.Value {
+	switch v := val.(type) {
+	case *ast.String:
+		str := *v
+		str.Value = strings.Replace(v.Value, routeValuePlaceholder, value, -1)
+		str.Data = []rune(strings.Replace(string(v.Data),
+			routeValuePlaceholder, value, -1))
+		return &str
+	case *ast.Array:
+		ary := *v
+		ary.Value = make([]ast.Value, len(v.Value))
+		for i, elem := range v.Value {
+			ary.Value[i] = substituteValue(elem, value)
+		}
+		return &ary
+	}
+	return val
+}
+
+// Routes returns the output instances created by Route so far, ordered by
+// alias.
+func (ro *RunningOutput) Routes() []*RunningOutput {
+	ro.route.Lock()
+	defer ro.route.Unlock()
+
+	routes := make([]*RunningOutput, 0, len(ro.route.instances))
+	for _, instance := range ro.route.instances {
+		routes = append(routes, instance)
+	}
+	sort.Sort(byAlias(routes))
+	return routes
+}
+
+type byAlias []*RunningOutput
+
+func (s byAlias) Len() int           { return len(s) }
+func (s byAlias) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byAlias) Less(i, j int) bool { return s[i].Alias < s[j].Alias }
+
+// popRoute removes the route_tag, route_values and route_max_instances
+// options from an output table, and sets them on the output
+func popRoute(tbl *ast.Table, ro *RunningOutput) error {
+	ro.RouteMaxInstances = DefaultRouteMaxInstances
+	if node, ok := tbl.Fields["route_tag"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				ro.RouteTag = str.Value
+			}
+		}
+	}
+	if node, ok := tbl.Fields["route_values"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						ro.RouteValues = append(ro.RouteValues, str.Value)
+					}
+				}
+			}
+		}
+	}
+	if node, ok := tbl.Fields["route_max_instances"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				n, err := integer.Int()
+				if err != nil {
+					return err
+				}
+				ro.RouteMaxInstances = int(n)
+			}
+		}
+	}
+	delete(tbl.Fields, "route_tag")
+	delete(tbl.Fields, "route_values")
+	delete(tbl.Fields, "route_max_instances")
+	return nil
+}
@@ -0,0 +1,289 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/plugins"
+
+	"github.com/naoina/toml"
+	tomlast "github.com/naoina/toml/ast"
+)
+
+// GenerateSampleConfig generates a sample config from the fields of a plugin
+// or output struct, in the format of the hand-written SampleConfig strings.
+// Keys are named by their toml tag, and every key is commented out with its
+// default value, preceded by the field's doc tag if it has one, ie
+// `doc:"connection timeout"`. Fields that are slices of structs are generated as arrays of tables.
+func GenerateSampleConfig(name string, v interface{}, op string) string {
+	var buf bytes.Buffer
+	generateTable(&buf, op+"."+name, reflect.ValueOf(v))
+	return buf.String()
+}
+
+var (
+	durationType = reflect.TypeOf(internal.Duration{})
+	sizeType     = reflect.TypeOf(internal.Size{})
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+func generateTable(buf *bytes.Buffer, path string, rv reflect.Value) {
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv = reflect.New(rv.Type().Elem())
+		}
+		rv = rv.Elem()
+	}
+	rt := rv.Type()
+
+	var tables []reflect.StructField
+	for i := 0; i < rt.NumField(); i++ {
+		ft := rt.Field(i)
+		if !ast.IsExported(ft.Name) || ft.Anonymous {
+			continue
+		}
+		if ft.Tag.Get("toml") == "-" {
+			continue
+		}
+		if isTableArray(ft.Type) {
+			tables = append(tables, ft)
+			continue
+		}
+
+		value, ok := sampleValue(rv.Field(i))
+		if !ok {
+			continue
+		}
+		if doc := ft.Tag.Get("doc"); doc != "" {
+			fmt.Fprintf(buf, "\n  # %s", doc)
+		}
+		fmt.Fprintf(buf, "\n  # %s = %s", keyName(rt, ft), value)
+	}
+	buf.WriteString("\n")
+
+	for _, ft := range tables {
+		if doc := ft.Tag.Get("doc"); doc != "" {
+			fmt.Fprintf(buf, "\n  # %s", doc)
+		}
+		subpath := path + "." + keyName(rt, ft)
+		fmt.Fprintf(buf, "\n  # [[%s]]", subpath)
+		generateTable(buf, subpath, reflect.New(elemType(ft.Type)))
+	}
+}
+
+// keyName returns the config key of a field: its toml tag, or its name in
+// snake case if the decoder maps that back to the field, or its name as is.
+func keyName(rt reflect.Type, ft reflect.StructField) string {
+	if tag := strings.Split(ft.Tag.Get("toml"), ",")[0]; tag != "" {
+		return tag
+	}
+	key := snakeCase(ft.Name)
+	if f, ok := rt.FieldByName(toCamelCase(key)); ok && f.Name == ft.Name {
+		return key
+	}
+	return ft.Name
+}
+
+// snakeCase converts a Go name to snake case, keeping acronyms together,
+// ie "ZookeeperPeers" to "zookeeper_peers" and "HTTPTimeout" to
+// "http_timeout".
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var out []rune
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 &&
+			(unicode.IsLower(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			out = append(out, '_')
+		}
+		out = append(out, unicode.ToLower(r))
+	}
+	return string(out)
+}
+
+// toCamelCase is how the toml decoder maps a snake case key to a field name
+func toCamelCase(s string) string {
+	var out []rune
+	upper := true
+	for _, r := range s {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+func isTableArray(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && elemType(t).Kind() == reflect.Struct &&
+		elemType(t) != timeType
+}
+
+func elemType(t reflect.Type) reflect.Type {
+	t = t.Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// sampleValue formats the value of a field as a toml value, it returns false
+// for fields that can't be set from a config file.
+func sampleValue(v reflect.Value) (string, bool) {
+	switch v.Type() {
+	case durationType:
+		return strconv.Quote(v.Interface().(internal.Duration).Duration.String()), true
+	case sizeType:
+		return strconv.FormatInt(v.Interface().(internal.Size).Size, 10), true
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String()), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		s := strconv.FormatFloat(v.Float(), 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s, true
+	case reflect.Slice:
+		values := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			value, ok := sampleValue(v.Index(i))
+			if !ok {
+				return "", false
+			}
+			values = append(values, value)
+		}
+		if v.Len() == 0 {
+			if _, ok := sampleValue(reflect.New(v.Type().Elem()).Elem()); !ok {
+				return "", false
+			}
+		}
+		return "[" + strings.Join(values, ", ") + "]", true
+	}
+	return "", false
+}
+
+var (
+	sampleHeader = regexp.MustCompile(`^\s*#?\s*(\[\[?)\s*([\w.]+)\s*\]\]?\s*$`)
+	sampleKey    = regexp.MustCompile(
+		`^\s*#?\s*([A-Za-z_][A-Za-z0-9_]*)\s*=\s*((?:["'\[\d+-]|true|false).*)$`)
+)
+
+// CheckSampleConfig checks that every key of a hand-written sample config,
+// commented out or not, is a config option of the plugin or output it
+// belongs to, with a value of the right type. It returns an error per key
+// that isn't.
+func CheckSampleConfig(name string, v interface{}, op string, sample string) []error {
+	var errs []error
+	root := op + "." + name
+	// the headers of the table a key is in, and of its parent tables
+	var headers []string
+	var paths []string
+
+	lines := strings.Split(sample, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if m := sampleHeader.FindStringSubmatch(line); m != nil {
+			path := m[2]
+			for len(paths) > 0 && !strings.HasPrefix(path, paths[len(paths)-1]+".") {
+				paths = paths[:len(paths)-1]
+				headers = headers[:len(headers)-1]
+			}
+			if path == root {
+				paths, headers = nil, nil
+			} else {
+				rel := strings.TrimPrefix(path, root+".")
+				paths = append(paths, path)
+				headers = append(headers,
+					m[1]+rel+strings.Repeat("]", len(m[1])))
+			}
+			continue
+		}
+
+		m := sampleKey.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		value := stripComment(m[2])
+		// values of multi-line arrays continue on the next lines
+		for strings.Count(value, "[") > strings.Count(value, "]") && i+1 < len(lines) {
+			i++
+			value += " " + stripComment(
+				strings.TrimLeft(strings.TrimSpace(lines[i]), "# "))
+		}
+
+		doc := strings.Join(headers, "\n") + "\n" + m[1] + " = " + value + "\n"
+		if err := checkSampleKey(name, v, op, doc); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %s: %s", op, name,
+				strings.TrimSpace(line), err))
+		}
+	}
+	return errs
+}
+
+// stripComment removes a trailing comment from a toml value
+func stripComment(value string) string {
+	inString := false
+	for i, r := range value {
+		switch r {
+		case '"':
+			inString = !inString
+		case '#':
+			if !inString {
+				return strings.TrimSpace(value[:i])
+			}
+		}
+	}
+	return strings.TrimSpace(value)
+}
+
+func checkSampleKey(name string, v interface{}, op string, doc string) error {
+	tbl, err := toml.Parse([]byte(doc))
+	if err != nil {
+		return err
+	}
+
+	// unmarshal into a fresh instance, as the config loader would
+	fresh := reflect.New(reflect.TypeOf(v).Elem()).Interface()
+	switch op {
+	case "plugins":
+		_, err = applyPlugin(name, tbl, fresh.(plugins.Plugin))
+		return err
+	default:
+		return applyOutputTable(tbl, fresh)
+	}
+}
+
+// applyOutputTable unmarshals an output table the way addOutput does,
+// ignoring the options that belong to the agent.
+func applyOutputTable(tbl *tomlast.Table, o interface{}) error {
+	popAlias(tbl)
+	if err := popRoute(tbl, &RunningOutput{}); err != nil {
+		return err
+	}
+	if _, _, err := popFailover(tbl); err != nil {
+		return err
+	}
+	return toml.UnmarshalTable(tbl, o)
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/influxdb/telegraf/outputs"
+	_ "github.com/influxdb/telegraf/outputs/all"
+	"github.com/influxdb/telegraf/plugins"
+	_ "github.com/influxdb/telegraf/plugins/all"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSampleConfigKeys fails when a hand-written sample config has a key
+// that isn't an option of its plugin or output.
+func TestSampleConfigKeys(t *testing.T) {
+	var names []string
+	for name := range plugins.Plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := plugins.Plugins[name]()
+		for _, err := range CheckSampleConfig(name, p, "plugins", p.SampleConfig()) {
+			t.Error(err)
+		}
+	}
+
+	names = nil
+	for name := range outputs.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		o := outputs.Outputs[name]()
+		for _, err := range CheckSampleConfig(name, o, "outputs", o.SampleConfig()) {
+			t.Error(err)
+		}
+	}
+}
+
+// TestGenerateSampleConfig checks that the generated sample configs are
+// valid configs of their plugin or output.
+func TestGenerateSampleConfig(t *testing.T) {
+	for name, creator := range plugins.Plugins {
+		p := creator()
+		sample := GenerateSampleConfig(name, p, "plugins")
+		for _, err := range CheckSampleConfig(name, p, "plugins", sample) {
+			t.Error(err)
+		}
+	}
+	for name, creator := range outputs.Outputs {
+		o := creator()
+		sample := GenerateSampleConfig(name, o, "outputs")
+		for _, err := range CheckSampleConfig(name, o, "outputs", sample) {
+			t.Error(err)
+		}
+	}
+}
+
+func TestGenerateSampleConfig_Execd(t *testing.T) {
+	sample := GenerateSampleConfig("execd", plugins.Plugins["execd"](), "plugins")
+
+	assert.Contains(t, sample, "\n  # how to ask for points each interval: stdin, SIGUSR1 or none"+
+		"\n  # signal = \"stdin\"")
+	assert.Contains(t, sample, "\n  # restart_delay = \"10s\"")
+	assert.Contains(t, sample, "\n  # tag_keys = []")
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+// pluginStatus is the result of the last Gather of a plugin
+type pluginStatus struct {
+	sync.Mutex
+	lastGather time.Time
+	lastErr    error
+}
+
+// SetGatherResult records the time and the result of a Gather
+func (rp *RunningPlugin) SetGatherResult(t time.Time, err error) {
+	rp.status.Lock()
+	defer rp.status.Unlock()
+	rp.status.lastGather = t
+	rp.status.lastErr = err
+}
+
+// GatherResult returns the time and the result of the last Gather, the time
+// is zero if the plugin hasn't been gathered yet.
+func (rp *RunningPlugin) GatherResult() (time.Time, error) {
+	rp.status.Lock()
+	defer rp.status.Unlock()
+	return rp.status.lastGather, rp.status.lastErr
+}
+
+// outputStatus tracks the writes to an output
+type outputStatus struct {
+	sync.Mutex
+	lastWrite    time.Time
+	lastErr      error
+	failedWrites int
+	buffered     int
+	dropped      int
+}
+
+// SetWriteResult records the time and the result of a Write, counting the
+// consecutive failures.
+func (ro *RunningOutput) SetWriteResult(t time.Time, err error) {
+	ro.status.Lock()
+	defer ro.status.Unlock()
+	ro.status.lastWrite = t
+	ro.status.lastErr = err
+	if err != nil {
+		ro.status.failedWrites++
+	} else {
+		ro.status.failedWrites = 0
+	}
+}
+
+// AddBuffered adds n, which may be negative, to the number of points
+// waiting to be written to the output
+func (ro *RunningOutput) AddBuffered(n int) {
+	ro.status.Lock()
+	defer ro.status.Unlock()
+	ro.status.buffered += n
+}
+
+// AddDropped counts n points that were dropped instead of being written to
+// the output
+func (ro *RunningOutput) AddDropped(n int) {
+	ro.status.Lock()
+	defer ro.status.Unlock()
+	ro.status.dropped += n
+}
+
+// OutputStatus is a snapshot of the writes to an output
+type OutputStatus struct {
+	LastWrite    time.Time
+	LastErr      error
+	FailedWrites int
+	Buffered     int
+	Dropped      int
+}
+
+// Status returns a snapshot of the writes to the output
+func (ro *RunningOutput) Status() OutputStatus {
+	ro.status.Lock()
+	defer ro.status.Unlock()
+	return OutputStatus{
+		LastWrite:    ro.status.lastWrite,
+		LastErr:      ro.status.lastErr,
+		FailedWrites: ro.status.failedWrites,
+		Buffered:     ro.status.buffered,
+		Dropped:      ro.status.dropped,
+	}
+}
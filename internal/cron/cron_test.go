@@ -0,0 +1,76 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		from string
+		next string
+	}{
+		// every hour at :05
+		{"5 * * * *", "2015-12-01 10:04", "2015-12-01 10:05"},
+		{"5 * * * *", "2015-12-01 10:05", "2015-12-01 11:05"},
+		{"5 * * * *", "2015-12-31 23:30", "2016-01-01 00:05"},
+		// 02:00 daily
+		{"0 2 * * *", "2015-12-01 10:00", "2015-12-02 02:00"},
+		{"@daily", "2015-12-01 10:00", "2015-12-02 00:00"},
+		{"@hourly", "2015-12-01 10:00", "2015-12-01 11:00"},
+		// steps, ranges and lists
+		{"*/15 * * * *", "2015-12-01 10:16", "2015-12-01 10:30"},
+		{"0 9-17/4 * * *", "2015-12-01 14:00", "2015-12-01 17:00"},
+		{"0 0 1,15 * *", "2015-12-02 00:00", "2015-12-15 00:00"},
+		// names, and Sunday as 7
+		{"0 0 * * mon-fri", "2015-12-05 12:00", "2015-12-07 00:00"},
+		{"0 0 * * 7", "2015-12-01 00:00", "2015-12-06 00:00"},
+		{"0 0 1 feb *", "2015-12-01 00:00", "2016-02-01 00:00"},
+		// either day of month or day of week
+		{"0 0 13 * fri", "2015-12-01 00:00", "2015-12-04 00:00"},
+		// leap days
+		{"0 0 29 2 *", "2015-03-01 00:00", "2016-02-29 00:00"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, date(tt.next), s.Next(date(tt.from)),
+			"%s from %s", tt.expr, tt.from)
+	}
+}
+
+func TestNext_Never(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(date("2015-12-01 00:00")).IsZero())
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -2,8 +2,11 @@ package internal
 
 import (
 	"bufio"
+	"crypto/rand"
 	"errors"
+	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -25,6 +28,44 @@ func (d *Duration) UnmarshalTOML(b []byte) error {
 	return nil
 }
 
+// Size just wraps an int64 number of bytes. In the config file it can be
+// given as an integer, or as a string with a unit, ie "10MB".
+type Size struct {
+	Size int64
+}
+
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"GB", 1024 * 1024 * 1024},
+	{"MB", 1024 * 1024},
+	{"KB", 1024},
+	{"B", 1},
+}
+
+// UnmarshalTOML parses the size from the TOML config file
+func (s *Size) UnmarshalTOML(b []byte) error {
+	str := strings.Trim(string(b), `"'`)
+
+	factor := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(strings.ToUpper(str), unit.suffix) {
+			factor = unit.factor
+			str = strings.TrimSpace(str[:len(str)-len(unit.suffix)])
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid size %s", string(b))
+	}
+
+	s.Size = n * factor
+	return nil
+}
+
 var NotImplementedError = errors.New("not implemented yet")
 
 // ReadLines reads contents from a file and splits them by new lines.
@@ -61,3 +102,15 @@ func ReadLinesOffsetN(filename string, offset uint, n int) ([]string, error) {
 
 	return ret, nil
 }
+
+// RandomString returns a random string of n alphanumeric characters, ie to
+// make up a client id
+func RandomString(n int) string {
+	const alphanum = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	var bytes = make([]byte, n)
+	rand.Read(bytes)
+	for i, b := range bytes {
+		bytes[i] = alphanum[b%byte(len(alphanum))]
+	}
+	return string(bytes)
+}
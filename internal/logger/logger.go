@@ -0,0 +1,178 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdb/telegraf/internal/secret"
+)
+
+// Level is the severity of a log line
+type Level int
+
+const (
+	DEBUG Level = iota
+	INFO
+	WARN
+	ERROR
+)
+
+// prefixes are written in front of every log line so that the level can be
+// recovered by the writer, and so that log files can be grepped. Lines
+// written with the standard log package can use them as well, ie,
+//     log.Printf("E! something went wrong")
+var prefixes = map[Level]string{
+	DEBUG: "D!",
+	INFO:  "I!",
+	WARN:  "W!",
+	ERROR: "E!",
+}
+
+// Config configures where log lines are written and which levels are kept
+type Config struct {
+	// Debug enables debug level lines
+	Debug bool
+	// Quiet suppresses everything below error level
+	Quiet bool
+	// Logfile is the file to write to, stderr is used if empty
+	Logfile string
+	// RotationMaxSize rotates the log file once it would grow past this many
+	// bytes, 0 disables rotation
+	RotationMaxSize int64
+	// RotationMaxArchives is the number of rotated log files to keep
+	RotationMaxArchives int
+}
+
+// Setup redirects the standard logger, and so every Logger, according to the
+// given config.
+func Setup(c Config) error {
+	var w io.Writer = os.Stderr
+	if c.Logfile != "" {
+		rf, err := newRotatingFile(c.Logfile, c.RotationMaxSize,
+			c.RotationMaxArchives)
+		if err != nil {
+			return err
+		}
+		w = rf
+	}
+
+	level := INFO
+	if c.Debug {
+		level = DEBUG
+	}
+	if c.Quiet {
+		level = ERROR
+	}
+
+	log.SetFlags(0)
+	log.SetOutput(&levelWriter{w: w, level: level})
+	return nil
+}
+
+// levelWriter drops lines below the configured level and timestamps the rest,
+// redacting any secrets from the config file. Lines without a level prefix
+// are treated as INFO.
+type levelWriter struct {
+	sync.Mutex
+	w     io.Writer
+	level Level
+}
+
+func (lw *levelWriter) Write(b []byte) (int, error) {
+	if lineLevel(b) < lw.level {
+		return len(b), nil
+	}
+
+	lw.Lock()
+	defer lw.Unlock()
+	ts := time.Now().UTC().Format(time.RFC3339)
+	line := ts + " " + secret.Redact(string(b))
+	if _, err := lw.w.Write([]byte(line)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func lineLevel(b []byte) Level {
+	if len(b) < 3 || b[1] != '!' || b[2] != ' ' {
+		return INFO
+	}
+	switch b[0] {
+	case 'D':
+		return DEBUG
+	case 'W':
+		return WARN
+	case 'E':
+		return ERROR
+	}
+	return INFO
+}
+
+// Logger writes leveled log lines tagged with the name of its owner, usually
+// a plugin or output, ie,
+//     2015-12-01T10:00:00Z E! [plugins.mysql] Error in plugin: ...
+// A nil Logger is valid and writes untagged lines.
+type Logger struct {
+	name string
+}
+
+// New returns a Logger that tags every line with the given name
+func New(name string) *Logger {
+	return &Logger{name: name}
+}
+
+// Name returns the name this Logger tags lines with
+func (l *Logger) Name() string {
+	if l == nil {
+		return ""
+	}
+	return l.name
+}
+
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	l.printf(DEBUG, format, v...)
+}
+
+func (l *Logger) Infof(format string, v ...interface{}) {
+	l.printf(INFO, format, v...)
+}
+
+func (l *Logger) Warnf(format string, v ...interface{}) {
+	l.printf(WARN, format, v...)
+}
+
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	l.printf(ERROR, format, v...)
+}
+
+func (l *Logger) printf(level Level, format string, v ...interface{}) {
+	msg := strings.TrimRight(fmt.Sprintf(format, v...), "\n")
+	if name := l.Name(); name != "" {
+		msg = "[" + name + "] " + msg
+	}
+	log.Print(prefixes[level] + " " + msg)
+}
+
+// agent is the Logger used for lines that don't belong to a plugin or output
+var agent *Logger
+
+func Debugf(format string, v ...interface{}) {
+	agent.printf(DEBUG, format, v...)
+}
+
+func Infof(format string, v ...interface{}) {
+	agent.printf(INFO, format, v...)
+}
+
+func Warnf(format string, v ...interface{}) {
+	agent.printf(WARN, format, v...)
+}
+
+func Errorf(format string, v ...interface{}) {
+	agent.printf(ERROR, format, v...)
+}
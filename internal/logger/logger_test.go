@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelWriter(t *testing.T) {
+	var buf bytes.Buffer
+	lw := &levelWriter{w: &buf, level: INFO}
+
+	lw.Write([]byte("D! [plugins.cpu] debug line\n"))
+	lw.Write([]byte("I! [plugins.cpu] info line\n"))
+	lw.Write([]byte("E! [plugins.cpu] error line\n"))
+	lw.Write([]byte("no prefix line\n"))
+
+	out := buf.String()
+	assert.NotContains(t, out, "debug line")
+	assert.Contains(t, out, "I! [plugins.cpu] info line")
+	assert.Contains(t, out, "E! [plugins.cpu] error line")
+	assert.Contains(t, out, "no prefix line")
+}
+
+func TestLevelWriterQuiet(t *testing.T) {
+	var buf bytes.Buffer
+	lw := &levelWriter{w: &buf, level: ERROR}
+
+	lw.Write([]byte("W! [outputs.influxdb] warn line\n"))
+	lw.Write([]byte("no prefix line\n"))
+	lw.Write([]byte("E! [outputs.influxdb] error line\n"))
+
+	out := buf.String()
+	assert.NotContains(t, out, "warn line")
+	assert.NotContains(t, out, "no prefix line")
+	assert.Contains(t, out, "error line")
+}
+
+func TestLoggerTagsName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-logger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	logfile := filepath.Join(dir, "telegraf.log")
+	require.NoError(t, Setup(Config{Debug: true, Logfile: logfile}))
+	defer Setup(Config{})
+
+	New("plugins.mysql").Errorf("connection refused")
+	New("plugins.mysql").Debugf("gathered")
+	var l *Logger
+	l.Infof("untagged")
+
+	b, err := ioutil.ReadFile(logfile)
+	require.NoError(t, err)
+	out := string(b)
+	assert.Contains(t, out, "E! [plugins.mysql] connection refused\n")
+	assert.Contains(t, out, "D! [plugins.mysql] gathered\n")
+	assert.Contains(t, out, "I! untagged\n")
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-logger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "telegraf.log")
+	rf, err := newRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := rf.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	current, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fourth\n", string(current))
+
+	archive, err := ioutil.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(archive))
+
+	archive, err = ioutil.ReadFile(path + ".2")
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", string(archive))
+
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// rotatingFile is an io.Writer to a log file that is rotated once it would
+// grow past maxSize bytes. Rotated files are renamed to <path>.1, <path>.2,
+// etc, with <path>.1 being the most recent, and only maxArchives of them are
+// kept.
+type rotatingFile struct {
+	sync.Mutex
+
+	path        string
+	maxSize     int64
+	maxArchives int
+
+	file *os.File
+	size int64
+}
+
+func newRotatingFile(
+	path string,
+	maxSize int64,
+	maxArchives int,
+) (*rotatingFile, error) {
+	rf := &rotatingFile{
+		path:        path,
+		maxSize:     maxSize,
+		maxArchives: maxArchives,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.file = f
+	rf.size = info.Size()
+	return nil
+}
+
+func (rf *rotatingFile) Write(b []byte) (int, error) {
+	rf.Lock()
+	defer rf.Unlock()
+
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(b)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.file.Write(b)
+	rf.size += int64(n)
+	return n, err
+}
+
+// rotate shifts every archive up by one, dropping the oldest, and starts a
+// new, empty log file.
+func (rf *rotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+
+	if rf.maxArchives > 0 {
+		os.Remove(rf.archive(rf.maxArchives))
+		for i := rf.maxArchives - 1; i > 0; i-- {
+			os.Rename(rf.archive(i), rf.archive(i+1))
+		}
+		if err := os.Rename(rf.path, rf.archive(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(rf.path); err != nil {
+		return err
+	}
+
+	return rf.open()
+}
+
+func (rf *rotatingFile) archive(n int) string {
+	return fmt.Sprintf("%s.%d", rf.path, n)
+}
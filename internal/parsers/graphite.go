@@ -0,0 +1,50 @@
+package parsers
+
+import (
+	"strings"
+
+	"github.com/influxdb/influxdb/models"
+	"github.com/influxdb/influxdb/services/graphite"
+)
+
+// GraphiteParser parses Graphite plaintext lines, "<path> <value>
+// [<timestamp>]", mapping the dotted path to a measurement, tags and a field
+// with the templates of InfluxDB's graphite service.
+type GraphiteParser struct {
+	parser *graphite.Parser
+}
+
+// NewGraphiteParser returns a parser for the given templates, whose
+// measurement parts are joined with separator
+func NewGraphiteParser(templates []string, separator string) (*GraphiteParser, error) {
+	if separator == "" {
+		separator = graphite.DefaultSeparator
+	}
+	// the default template of the graphite package always joins with ".",
+	// a template without a filter replaces it
+	templates = append([]string{"measurement*"}, templates...)
+	p, err := graphite.NewParserWithOptions(graphite.Options{
+		Templates: templates,
+		Separator: separator,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &GraphiteParser{parser: p}, nil
+}
+
+func (p *GraphiteParser) Parse(buf []byte) ([]models.Point, error) {
+	var points []models.Point
+	for _, line := range strings.Split(string(buf), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		pt, err := p.parser.Parse(line)
+		if err != nil {
+			return points, err
+		}
+		points = append(points, pt)
+	}
+	return points, nil
+}
@@ -0,0 +1,307 @@
+package parsers
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdb/influxdb/models"
+)
+
+// maxGrokDepth bounds how deeply patterns can refer to each other, so that
+// a pattern that refers to itself is an error rather than a hang
+const maxGrokDepth = 32
+
+// grokReference matches a reference to a pattern, ie,
+//     %{NUMBER}, %{NUMBER:bytes} or %{NUMBER:bytes:int}
+var grokReference = regexp.MustCompile(`%{(\w+)(?::([\w.-]+))?(?::([^}]+))?}`)
+
+// timestampLayouts are the layouts of the ts-<layout> modifier that have a
+// name. Other layouts are given as a Go time layout, ie ts-2006-01-02.
+var timestampLayouts = map[string]string{
+	"ansic":       time.ANSIC,
+	"unixdate":    time.UnixDate,
+	"rubydate":    time.RubyDate,
+	"rfc822":      time.RFC822,
+	"rfc822z":     time.RFC822Z,
+	"rfc850":      time.RFC850,
+	"rfc1123":     time.RFC1123,
+	"rfc1123z":    time.RFC1123Z,
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"stamp":       time.Stamp,
+	"httpd":       "02/Jan/2006:15:04:05 -0700",
+	"syslog":      "Jan _2 15:04:05",
+}
+
+// GrokParser parses lines with grok patterns: regular expressions that can
+// refer to named patterns, such as %{IPORHOST}, and capture what they match
+// as %{PATTERN:name:modifier}. The modifier says what a capture becomes:
+//     string (the default)   a string field
+//     int, float             a number field
+//     duration               an int field of nanoseconds, from a Go duration
+//                            like "250ms" or a number of seconds like "0.25"
+//     tag                    a tag
+//     drop                   nothing
+//     ts-<layout>            the timestamp of the point, see timestampLayouts,
+//                            or ts-epoch for seconds since the epoch
+// A line is matched against each of Patterns in order, and the first match
+// is turned into a point. Lines that match none are skipped.
+type GrokParser struct {
+	MetricName string
+
+	// Patterns are the patterns lines are matched against
+	Patterns []string
+
+	// CustomPatterns defines patterns, one per line as NAME followed by its
+	// regular expression, in addition to DefaultGrokPatterns
+	CustomPatterns string
+
+	// CustomPatternFiles are files of patterns in the same format
+	CustomPatternFiles []string
+
+	compiled []*grokPattern
+}
+
+type grokPattern struct {
+	re       *regexp.Regexp
+	captures map[string]grokCapture
+}
+
+type grokCapture struct {
+	name     string
+	modifier string
+}
+
+// Compile compiles the patterns of the parser, it is called by NewParser
+func (p *GrokParser) Compile() error {
+	if len(p.Patterns) == 0 {
+		return fmt.Errorf("grok data format needs at least one pattern")
+	}
+
+	defs := make(map[string]string)
+	if err := addGrokPatterns(defs, DefaultGrokPatterns); err != nil {
+		return err
+	}
+	for _, path := range p.CustomPatternFiles {
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("unable to read patterns file: %s", err)
+		}
+		if err := addGrokPatterns(defs, string(buf)); err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+	}
+	if err := addGrokPatterns(defs, p.CustomPatterns); err != nil {
+		return err
+	}
+
+	p.compiled = nil
+	for _, pattern := range p.Patterns {
+		gp := &grokPattern{captures: make(map[string]grokCapture)}
+		expr, err := gp.expand(pattern, defs, 0)
+		if err != nil {
+			return fmt.Errorf("grok pattern %q: %s", pattern, err)
+		}
+		gp.re, err = regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("grok pattern %q: %s", pattern, err)
+		}
+		for _, c := range gp.captures {
+			if err := checkModifier(c.modifier); err != nil {
+				return fmt.Errorf("grok pattern %q: %s", pattern, err)
+			}
+		}
+		p.compiled = append(p.compiled, gp)
+	}
+	return nil
+}
+
+// addGrokPatterns adds the pattern definitions of text to defs
+func addGrokPatterns(defs map[string]string, text string) error {
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid pattern definition %q, must be NAME "+
+				"followed by a pattern", line)
+		}
+		defs[parts[0]] = strings.TrimSpace(parts[1])
+	}
+	return scanner.Err()
+}
+
+// expand replaces the pattern references of a pattern with the regular
+// expressions they refer to, and records the captures as named groups.
+func (gp *grokPattern) expand(
+	pattern string,
+	defs map[string]string,
+	depth int,
+) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("patterns are nested more than %d deep", maxGrokDepth)
+	}
+
+	var rerr error
+	expr := grokReference.ReplaceAllStringFunc(pattern, func(ref string) string {
+		m := grokReference.FindStringSubmatch(ref)
+		def, ok := defs[m[1]]
+		if !ok {
+			rerr = fmt.Errorf("undefined pattern %s", m[1])
+			return ref
+		}
+		sub, err := gp.expand(def, defs, depth+1)
+		if err != nil {
+			rerr = err
+			return ref
+		}
+		if m[2] == "" {
+			return "(?:" + sub + ")"
+		}
+		group := fmt.Sprintf("c%d", len(gp.captures))
+		gp.captures[group] = grokCapture{name: m[2], modifier: m[3]}
+		return "(?P<" + group + ">" + sub + ")"
+	})
+	return expr, rerr
+}
+
+func checkModifier(modifier string) error {
+	switch modifier {
+	case "", "string", "int", "float", "duration", "tag", "drop":
+		return nil
+	}
+	if strings.HasPrefix(modifier, "ts-") && len(modifier) > 3 {
+		return nil
+	}
+	return fmt.Errorf("unknown modifier %q", modifier)
+}
+
+func (p *GrokParser) Parse(buf []byte) ([]models.Point, error) {
+	line := strings.TrimRight(string(buf), "\r\n")
+	for _, gp := range p.compiled {
+		values := gp.re.FindStringSubmatch(line)
+		if values == nil {
+			continue
+		}
+
+		pt, err := gp.point(p.MetricName, values)
+		if err != nil {
+			return nil, err
+		}
+		if pt == nil {
+			return nil, nil
+		}
+		return []models.Point{pt}, nil
+	}
+	return nil, nil
+}
+
+// point turns the values captured by a match into a point, it returns nil
+// if nothing was captured as a field
+func (gp *grokPattern) point(name string, values []string) (models.Point, error) {
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+	ts := time.Now()
+
+	for i, group := range gp.re.SubexpNames() {
+		value := values[i]
+		if group == "" || value == "" {
+			continue
+		}
+		c, ok := gp.captures[group]
+		if !ok {
+			// a named group of the pattern's own regular expression
+			fields[group] = value
+			continue
+		}
+
+		switch {
+		case c.modifier == "" || c.modifier == "string":
+			fields[c.name] = unquote(value)
+		case c.modifier == "int":
+			if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+				fields[c.name] = v
+			} else if v, err := strconv.ParseFloat(value, 64); err == nil {
+				fields[c.name] = int64(v)
+			} else {
+				return nil, fmt.Errorf("%s: %q is not an int", c.name, value)
+			}
+		case c.modifier == "float":
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %q is not a float", c.name, value)
+			}
+			fields[c.name] = v
+		case c.modifier == "duration":
+			v, err := parseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %q is not a duration", c.name, value)
+			}
+			fields[c.name] = int64(v)
+		case c.modifier == "tag":
+			tags[c.name] = value
+		case c.modifier == "drop":
+		case strings.HasPrefix(c.modifier, "ts-"):
+			t, err := parseTimestamp(c.modifier[3:], value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", c.name, err)
+			}
+			ts = t
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return models.NewPoint(name, tags, fields, ts)
+}
+
+// unquote removes the quotes around a quoted string, as matched by QS
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// parseDuration parses a Go duration, or a number of seconds
+func parseDuration(value string) (time.Duration, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return d, nil
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func parseTimestamp(layout string, value string) (time.Time, error) {
+	if layout == "epoch" {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%q is not an epoch timestamp", value)
+		}
+		return time.Unix(0, int64(seconds*float64(time.Second))), nil
+	}
+	if named, ok := timestampLayouts[layout]; ok {
+		layout = named
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// layouts without a year, such as syslog, are in the current year
+	if t.Year() == 0 {
+		t = t.AddDate(time.Now().Year(), 0, 0)
+	}
+	return t, nil
+}
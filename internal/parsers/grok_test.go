@@ -0,0 +1,156 @@
+package parsers
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrokCombinedLogFormat(t *testing.T) {
+	p, err := NewParser(&Config{
+		DataFormat: "grok",
+		MetricName: "access_log",
+		Patterns:   []string{"%{COMBINED_LOG_FORMAT}"},
+	})
+	require.NoError(t, err)
+
+	points, err := p.Parse([]byte(`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] ` +
+		`"GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" ` +
+		`"Mozilla/4.08 [en] (Win98; I ;Nav)"`))
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+
+	pt := points[0]
+	assert.Equal(t, "access_log", pt.Name())
+	assert.Equal(t, map[string]string{"verb": "GET", "resp_code": "200"},
+		map[string]string(pt.Tags()))
+	assert.Equal(t, map[string]interface{}{
+		"client_ip":    "127.0.0.1",
+		"ident":        "-",
+		"auth":         "frank",
+		"request":      "/apache_pb.gif",
+		"http_version": 1.0,
+		"resp_bytes":   int64(2326),
+		"referrer":     "http://www.example.com/start.html",
+		"agent":        "Mozilla/4.08 [en] (Win98; I ;Nav)",
+	}, map[string]interface{}(pt.Fields()))
+	assert.Equal(t, time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC),
+		pt.Time().UTC())
+}
+
+func TestGrokCommonLogFormat(t *testing.T) {
+	p, err := NewParser(&Config{
+		DataFormat: "grok",
+		MetricName: "access_log",
+		Patterns:   []string{"%{COMMON_LOG_FORMAT}"},
+	})
+	require.NoError(t, err)
+
+	// no response body
+	points, err := p.Parse([]byte(`::1 - - [10/Oct/2000:13:55:36 -0700] ` +
+		`"HEAD / HTTP/1.1" 304 -`))
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	_, ok := points[0].Fields()["resp_bytes"]
+	assert.False(t, ok)
+	assert.Equal(t, "::1", points[0].Fields()["client_ip"])
+}
+
+func TestGrokModifiers(t *testing.T) {
+	p, err := NewParser(&Config{
+		DataFormat: "grok",
+		MetricName: "app",
+		Patterns: []string{
+			`%{TIMESTAMP_ISO8601:ts:ts-2006-01-02 15:04:05} %{LOGLEVEL:level:tag} ` +
+				`%{WORD:handler} took %{NOTSPACE:took:duration} ` +
+				`\(%{NUMBER:rows:int} rows, %{NUMBER:ratio:float}, %{WORD:secret:drop}\)`,
+		},
+	})
+	require.NoError(t, err)
+
+	points, err := p.Parse([]byte(
+		"2015-12-01 10:00:00 INFO search took 250ms (12 rows, 0.5, hunter2)"))
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, "INFO", points[0].Tags()["level"])
+	assert.Equal(t, map[string]interface{}{
+		"handler": "search",
+		"took":    int64(250 * time.Millisecond),
+		"rows":    int64(12),
+		"ratio":   0.5,
+	}, map[string]interface{}(points[0].Fields()))
+	assert.Equal(t, time.Date(2015, 12, 1, 10, 0, 0, 0, time.UTC), points[0].Time())
+
+	// durations can be numbers of seconds too
+	points, err = p.Parse([]byte(
+		"2015-12-01 10:00:00 INFO search took 0.25 (12 rows, 0.5, hunter2)"))
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, int64(250*time.Millisecond), points[0].Fields()["took"])
+}
+
+func TestGrokCustomPatterns(t *testing.T) {
+	f, err := ioutil.TempFile("", "patterns")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("# queue names\nQUEUE [a-z]+-queue\n")
+	require.NoError(t, err)
+	f.Close()
+
+	p, err := NewParser(&Config{
+		DataFormat:         "grok",
+		MetricName:         "jobs",
+		CustomPatterns:     "JOB %{QUEUE:queue:tag} %{INT:depth:int}",
+		CustomPatternFiles: []string{f.Name()},
+		Patterns:           []string{"^%{JOB} at %{NUMBER:ts:ts-epoch}$", "^%{JOB}$"},
+	})
+	require.NoError(t, err)
+
+	points, err := p.Parse([]byte("mail-queue 3 at 1449000000.5"))
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, "mail-queue", points[0].Tags()["queue"])
+	assert.Equal(t, int64(3), points[0].Fields()["depth"])
+	assert.Equal(t, time.Unix(1449000000, 500000000), points[0].Time())
+
+	// the second pattern is tried when the first doesn't match
+	points, err = p.Parse([]byte("mail-queue 4"))
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, int64(4), points[0].Fields()["depth"])
+
+	// lines that match no pattern are skipped
+	points, err = p.Parse([]byte("something else"))
+	assert.NoError(t, err)
+	assert.Empty(t, points)
+}
+
+func TestGrokErrors(t *testing.T) {
+	for _, c := range []*Config{
+		{DataFormat: "grok", MetricName: "a"},
+		{DataFormat: "grok", Patterns: []string{"%{INT:a}"}},
+		{DataFormat: "grok", MetricName: "a", Patterns: []string{"%{NOPE:a}"}},
+		{DataFormat: "grok", MetricName: "a", Patterns: []string{"%{INT:a:hex}"}},
+		{DataFormat: "grok", MetricName: "a", Patterns: []string{"%{LOOP}"},
+			CustomPatterns: "LOOP %{LOOP}"},
+		{DataFormat: "grok", MetricName: "a", Patterns: []string{"%{INT:a}"},
+			CustomPatternFiles: []string{"/does/not/exist"}},
+	} {
+		_, err := NewParser(c)
+		assert.Error(t, err, "%+v", c)
+	}
+
+	// captures that don't convert are errors
+	p, err := NewParser(&Config{
+		DataFormat: "grok",
+		MetricName: "a",
+		Patterns:   []string{"%{WORD:a:int}"},
+	})
+	require.NoError(t, err)
+	_, err = p.Parse([]byte("abc"))
+	assert.Error(t, err)
+}
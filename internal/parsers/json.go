@@ -0,0 +1,77 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/influxdb/influxdb/models"
+)
+
+// JSONParser parses a JSON object, or an array of objects, into one point
+// per object. Nested objects are flattened with their keys joined by "_",
+// numbers and booleans become fields, and the string values of TagKeys
+// become tags. Other values are ignored.
+type JSONParser struct {
+	MetricName string
+	TagKeys    []string
+}
+
+func (p *JSONParser) Parse(buf []byte) ([]models.Point, error) {
+	buf = bytes.TrimSpace(buf)
+	if len(buf) == 0 {
+		return nil, nil
+	}
+
+	var objects []map[string]interface{}
+	if buf[0] == '[' {
+		if err := json.Unmarshal(buf, &objects); err != nil {
+			return nil, fmt.Errorf("unable to parse JSON: %s", err)
+		}
+	} else {
+		var object map[string]interface{}
+		if err := json.Unmarshal(buf, &object); err != nil {
+			return nil, fmt.Errorf("unable to parse JSON: %s", err)
+		}
+		objects = append(objects, object)
+	}
+
+	now := time.Now()
+	points := make([]models.Point, 0, len(objects))
+	for _, object := range objects {
+		tags := make(map[string]string)
+		for _, key := range p.TagKeys {
+			if value, ok := object[key].(string); ok {
+				tags[key] = value
+			}
+		}
+
+		fields := make(map[string]interface{})
+		flatten("", object, fields)
+		if len(fields) == 0 {
+			continue
+		}
+
+		pt, err := models.NewPoint(p.MetricName, tags, fields, now)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, pt)
+	}
+	return points, nil
+}
+
+func flatten(prefix string, v interface{}, fields map[string]interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, v := range t {
+			if prefix != "" {
+				k = prefix + "_" + k
+			}
+			flatten(k, v, fields)
+		}
+	case float64, bool:
+		fields[prefix] = t
+	}
+}
@@ -0,0 +1,83 @@
+package parsers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdb/influxdb/models"
+)
+
+// Parser parses metrics written in a data format into points
+type Parser interface {
+	// Parse parses a buffer holding one or more metrics
+	Parse(buf []byte) ([]models.Point, error)
+}
+
+// Config holds the data format options of the plugins that read metrics in
+// more than one format.
+type Config struct {
+	// DataFormat is "influx" (line protocol, the default), "json", "grok"
+	// or "graphite"
+	DataFormat string
+
+	// MetricName is the measurement name of formats that don't have one
+	MetricName string
+
+	// TagKeys are the json keys that are tags rather than fields
+	TagKeys []string
+
+	// Patterns, CustomPatterns and CustomPatternFiles are the patterns of
+	// the grok format, see GrokParser
+	Patterns           []string
+	CustomPatterns     string
+	CustomPatternFiles []string
+
+	// Templates and Separator map the paths of the graphite format to
+	// measurements, tags and fields, see GraphiteParser
+	Templates []string
+	Separator string
+}
+
+// NewParser returns a parser for the data format of the given config
+func NewParser(c *Config) (Parser, error) {
+	switch c.DataFormat {
+	case "influx", "":
+		return &InfluxParser{}, nil
+	case "json":
+		if c.MetricName == "" {
+			return nil, fmt.Errorf("json data format needs a metric name")
+		}
+		return &JSONParser{MetricName: c.MetricName, TagKeys: c.TagKeys}, nil
+	case "grok":
+		if c.MetricName == "" {
+			return nil, fmt.Errorf("grok data format needs a metric name")
+		}
+		p := &GrokParser{
+			MetricName:         c.MetricName,
+			Patterns:           c.Patterns,
+			CustomPatterns:     c.CustomPatterns,
+			CustomPatternFiles: c.CustomPatternFiles,
+		}
+		if err := p.Compile(); err != nil {
+			return nil, err
+		}
+		return p, nil
+	case "graphite":
+		return NewGraphiteParser(c.Templates, c.Separator)
+	}
+	return nil, fmt.Errorf("Unknown data format: %s", c.DataFormat)
+}
+
+// InfluxParser parses InfluxDB line protocol
+type InfluxParser struct {
+	// Precision is the unit of the timestamps: "n" (the default), "u",
+	// "ms", "s", "m" or "h"
+	Precision string
+}
+
+func (p *InfluxParser) Parse(buf []byte) ([]models.Point, error) {
+	if p.Precision == "" {
+		return models.ParsePoints(buf)
+	}
+	return models.ParsePointsWithPrecision(buf, time.Now().UTC(), p.Precision)
+}
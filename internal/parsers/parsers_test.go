@@ -0,0 +1,99 @@
+package parsers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfluxParser(t *testing.T) {
+	p, err := NewParser(&Config{})
+	require.NoError(t, err)
+
+	points, err := p.Parse([]byte("cpu,host=a usage=1.5\nmem free=10i\n"))
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, "cpu", points[0].Name())
+	assert.Equal(t, "a", points[0].Tags()["host"])
+	assert.Equal(t, 1.5, points[0].Fields()["usage"])
+	assert.Equal(t, int64(10), points[1].Fields()["free"])
+}
+
+func TestInfluxParserPrecision(t *testing.T) {
+	p := &InfluxParser{Precision: "s"}
+	points, err := p.Parse([]byte("cpu usage=1.5 1445000000\n"))
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, int64(1445000000), points[0].Time().Unix())
+}
+
+func TestJSONParser(t *testing.T) {
+	p, err := NewParser(&Config{
+		DataFormat: "json",
+		MetricName: "collector",
+		TagKeys:    []string{"host"},
+	})
+	require.NoError(t, err)
+
+	points, err := p.Parse([]byte(`{
+		"host": "a",
+		"comment": "ignored",
+		"load": 0.5,
+		"up": true,
+		"queue": {"depth": 3, "name": "jobs"}
+	}`))
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, "collector", points[0].Name())
+	assert.Equal(t, map[string]string{"host": "a"}, map[string]string(points[0].Tags()))
+	assert.Equal(t, map[string]interface{}{
+		"load":        0.5,
+		"up":          true,
+		"queue_depth": 3.0,
+	}, map[string]interface{}(points[0].Fields()))
+}
+
+func TestJSONParserArray(t *testing.T) {
+	p := &JSONParser{MetricName: "collector"}
+
+	points, err := p.Parse([]byte(`[{"a": 1}, {"a": 2}, {"b": "text"}]`))
+	require.NoError(t, err)
+	assert.Len(t, points, 2)
+
+	_, err = p.Parse([]byte(`{"a": `))
+	assert.Error(t, err)
+}
+
+func TestNewParserUnknownFormat(t *testing.T) {
+	_, err := NewParser(&Config{DataFormat: "xml"})
+	assert.Error(t, err)
+
+	_, err = NewParser(&Config{DataFormat: "json"})
+	assert.Error(t, err)
+}
+
+func TestGraphiteParser(t *testing.T) {
+	p, err := NewParser(&Config{
+		DataFormat: "graphite",
+		Templates:  []string{"servers.* .host.measurement.field"},
+		Separator:  "_",
+	})
+	require.NoError(t, err)
+
+	points, err := p.Parse([]byte("servers.web01.cpu.load 0.5 1449000000\n" +
+		"app.requests.count 10\n"))
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, "cpu", points[0].Name())
+	assert.Equal(t, "web01", points[0].Tags()["host"])
+	assert.Equal(t, 0.5, points[0].Fields()["load"])
+	assert.Equal(t, int64(1449000000), points[0].Time().Unix())
+
+	// the default template joins the whole path with the separator
+	assert.Equal(t, "app_requests_count", points[1].Name())
+	assert.Equal(t, 10.0, points[1].Fields()["value"])
+
+	_, err = p.Parse([]byte("app.requests.count ten"))
+	assert.Error(t, err)
+}
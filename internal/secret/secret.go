@@ -0,0 +1,91 @@
+package secret
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces secrets in log lines and debug output
+const Redacted = "<redacted>"
+
+// reference matches a secret reference within a config string, ie,
+//     @{file:/run/secrets/db}
+//     @{env:DB_PASS}
+// References can be embedded in a larger string, such as a DSN.
+var reference = regexp.MustCompile(`@\{(file|env):([^}]+)\}`)
+
+var (
+	mu      sync.RWMutex
+	secrets []string
+)
+
+// Resolve replaces every secret reference in s with the secret it points at.
+// Every resolved secret is remembered so that Redact can hide it later.
+func Resolve(s string) (string, error) {
+	var rerr error
+	resolved := reference.ReplaceAllStringFunc(s, func(ref string) string {
+		m := reference.FindStringSubmatch(ref)
+		value, err := lookup(m[1], m[2])
+		if err != nil {
+			rerr = err
+			return ref
+		}
+		register(value)
+		return value
+	})
+	if rerr != nil {
+		return "", rerr
+	}
+	return resolved, nil
+}
+
+func lookup(kind, key string) (string, error) {
+	switch kind {
+	case "file":
+		b, err := ioutil.ReadFile(key)
+		if err != nil {
+			return "", fmt.Errorf("Could not read secret file: %s", err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case "env":
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			return "", fmt.Errorf("Secret environment variable %s is not set", key)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("Unknown secret reference type: %s", kind)
+}
+
+// register remembers a secret. Secrets are kept longest first, so that a
+// secret that contains a shorter one is redacted as a whole.
+func register(value string) {
+	if value == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	i := 0
+	for ; i < len(secrets) && len(secrets[i]) >= len(value); i++ {
+		if secrets[i] == value {
+			return
+		}
+	}
+	secrets = append(secrets, "")
+	copy(secrets[i+1:], secrets[i:])
+	secrets[i] = value
+}
+
+// Redact replaces every secret resolved so far that appears in s
+func Redact(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, secret := range secrets {
+		s = strings.Replace(s, secret, Redacted, -1)
+	}
+	return s
+}
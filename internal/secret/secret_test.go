@@ -0,0 +1,81 @@
+package secret
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveEnv(t *testing.T) {
+	os.Setenv("TELEGRAF_SECRET_TEST", "envsecret")
+	defer os.Unsetenv("TELEGRAF_SECRET_TEST")
+
+	s, err := Resolve("user:@{env:TELEGRAF_SECRET_TEST}@tcp(localhost:3306)/")
+	require.NoError(t, err)
+	assert.Equal(t, "user:envsecret@tcp(localhost:3306)/", s)
+}
+
+func TestResolveFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "telegraf-secret")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("filesecret\n")
+	f.Close()
+
+	s, err := Resolve("@{file:" + f.Name() + "}")
+	require.NoError(t, err)
+	assert.Equal(t, "filesecret", s)
+}
+
+func TestResolveNoReference(t *testing.T) {
+	s, err := Resolve("plain password")
+	require.NoError(t, err)
+	assert.Equal(t, "plain password", s)
+}
+
+func TestResolveMissing(t *testing.T) {
+	os.Unsetenv("TELEGRAF_SECRET_MISSING")
+	_, err := Resolve("@{env:TELEGRAF_SECRET_MISSING}")
+	assert.Error(t, err)
+
+	_, err = Resolve("@{file:/nonexistent/telegraf/secret}")
+	assert.Error(t, err)
+}
+
+func TestRedact(t *testing.T) {
+	os.Setenv("TELEGRAF_SECRET_REDACT", "hunter2")
+	defer os.Unsetenv("TELEGRAF_SECRET_REDACT")
+
+	_, err := Resolve("@{env:TELEGRAF_SECRET_REDACT}")
+	require.NoError(t, err)
+
+	assert.Equal(t, "dial tcp: user:<redacted>@localhost failed",
+		Redact("dial tcp: user:hunter2@localhost failed"))
+}
+
+func TestRedactShortSecret(t *testing.T) {
+	os.Setenv("TELEGRAF_SECRET_SHORT", "pw1")
+	defer os.Unsetenv("TELEGRAF_SECRET_SHORT")
+	os.Setenv("TELEGRAF_SECRET_LONG", "pw1pw2")
+	defer os.Unsetenv("TELEGRAF_SECRET_LONG")
+
+	_, err := Resolve("@{env:TELEGRAF_SECRET_SHORT} @{env:TELEGRAF_SECRET_LONG}")
+	require.NoError(t, err)
+
+	// secrets are redacted whatever their length, longest first
+	assert.Equal(t, "user:<redacted> user:<redacted>",
+		Redact("user:pw1 user:pw1pw2"))
+}
+
+func TestRedactEmptySecret(t *testing.T) {
+	os.Setenv("TELEGRAF_SECRET_EMPTY", "")
+	defer os.Unsetenv("TELEGRAF_SECRET_EMPTY")
+
+	_, err := Resolve("@{env:TELEGRAF_SECRET_EMPTY}")
+	require.NoError(t, err)
+
+	assert.Equal(t, "cpu usage_idle=100", Redact("cpu usage_idle=100"))
+}
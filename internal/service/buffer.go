@@ -0,0 +1,92 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/influxdb/influxdb/models"
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+// DefaultPointBuffer is the number of points a Buffer holds at most, unless
+// the plugin's point_buffer is set.
+const DefaultPointBuffer = 100000
+
+// Buffer holds the points received by a service plugin until the next
+// Gather. Once it is full, further points are dropped until they are
+// gathered.
+type Buffer struct {
+	mu      sync.Mutex
+	points  []models.Point
+	max     int
+	dropped int
+}
+
+// SetMax sets the number of points the buffer holds at most, 0 for
+// DefaultPointBuffer
+func (b *Buffer) SetMax(max int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.max = max
+}
+
+// Add buffers points until the next Gather, and returns false if some of
+// them were dropped because the buffer is full.
+func (b *Buffer) Add(points ...models.Point) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := len(points)
+	if room := b.roomLocked(); n > room {
+		n = room
+	}
+	b.points = append(b.points, points[:n]...)
+	b.dropped += len(points) - n
+	return n == len(points)
+}
+
+// TryAdd buffers points until the next Gather if they all fit, and returns
+// false without buffering any of them otherwise, so that the sender can
+// retry them.
+func (b *Buffer) TryAdd(points ...models.Point) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(points) > b.roomLocked() {
+		return false
+	}
+	b.points = append(b.points, points...)
+	return true
+}
+
+func (b *Buffer) roomLocked() int {
+	max := b.max
+	if max <= 0 {
+		max = DefaultPointBuffer
+	}
+	return max - len(b.points)
+}
+
+// Len returns the number of buffered points
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.points)
+}
+
+// Gather adds the points buffered since the last Gather to acc, and warns
+// about the points that were dropped meanwhile.
+func (b *Buffer) Gather(acc plugins.Accumulator, log *logger.Logger) {
+	b.mu.Lock()
+	points, dropped := b.points, b.dropped
+	b.points, b.dropped = nil, 0
+	b.mu.Unlock()
+
+	for _, point := range points {
+		acc.AddFields(point.Name(), point.Fields(), point.Tags(), point.Time())
+	}
+	if dropped > 0 {
+		log.Warnf("Dropped %d metrics, the buffer is full, you may want to "+
+			"increase point_buffer", dropped)
+	}
+}
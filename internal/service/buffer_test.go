@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/models"
+	"github.com/influxdb/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func point(t *testing.T, value int) models.Point {
+	pt, err := models.NewPoint("test", nil,
+		map[string]interface{}{"value": value}, time.Unix(0, 0))
+	require.NoError(t, err)
+	return pt
+}
+
+func TestBufferDropsWhenFull(t *testing.T) {
+	var b Buffer
+	b.SetMax(2)
+
+	assert.True(t, b.Add(point(t, 1)))
+	assert.False(t, b.Add(point(t, 2), point(t, 3)))
+	assert.False(t, b.Add(point(t, 4)))
+	assert.Equal(t, 2, b.Len())
+
+	var acc testutil.Accumulator
+	b.Gather(&acc, nil)
+	require.Len(t, acc.Points, 2)
+	assert.Equal(t, int64(1), acc.Points[0].Fields["value"])
+	assert.Equal(t, int64(2), acc.Points[1].Fields["value"])
+
+	// gathering makes room again
+	assert.True(t, b.Add(point(t, 5)))
+	assert.Equal(t, 1, b.Len())
+}
+
+func TestBufferTryAdd(t *testing.T) {
+	var b Buffer
+	b.SetMax(2)
+
+	assert.True(t, b.TryAdd(point(t, 1)))
+	assert.False(t, b.TryAdd(point(t, 2), point(t, 3)))
+	assert.Equal(t, 1, b.Len())
+	assert.True(t, b.TryAdd(point(t, 2)))
+	assert.Equal(t, 2, b.Len())
+}
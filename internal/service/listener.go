@@ -0,0 +1,120 @@
+package service
+
+import (
+	"net"
+	"sync"
+)
+
+// Listener is the socket a service plugin listens on, and the connections
+// accepted on it. Once it is closed Stopping returns true, so that readers
+// can tell the errors caused by closing the socket from other errors.
+type Listener struct {
+	mu      sync.Mutex
+	stream  net.Listener
+	packets net.PacketConn
+	conns   map[net.Conn]bool
+	done    chan struct{}
+}
+
+// Listen sets the stream socket to accept connections on
+func (l *Listener) Listen(stream net.Listener) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stream = stream
+}
+
+// ListenPacket sets the packet socket to read from
+func (l *Listener) ListenPacket(packets net.PacketConn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.packets = packets
+}
+
+// Addr returns the address the plugin listens on, which has the actual
+// port when the configured port is 0
+func (l *Listener) Addr() net.Addr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.packets != nil {
+		return l.packets.LocalAddr()
+	}
+	if l.stream != nil {
+		return l.stream.Addr()
+	}
+	return nil
+}
+
+// Done returns a channel that is closed when the listener is closed
+func (l *Listener) Done() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.doneLocked()
+}
+
+func (l *Listener) doneLocked() chan struct{} {
+	if l.done == nil {
+		l.done = make(chan struct{})
+	}
+	return l.done
+}
+
+// Stopping returns true once the listener is closed
+func (l *Listener) Stopping() bool {
+	select {
+	case <-l.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// Track keeps a connection accepted on the listener, so that Close closes
+// it. Unless the listener is closed, or max connections are tracked already
+// while max is above 0, in which case false is returned.
+func (l *Listener) Track(conn net.Conn, max int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-l.doneLocked():
+		return false
+	default:
+	}
+	if max > 0 && len(l.conns) >= max {
+		return false
+	}
+	if l.conns == nil {
+		l.conns = make(map[net.Conn]bool)
+	}
+	l.conns[conn] = true
+	return true
+}
+
+// Untrack closes a connection and forgets it
+func (l *Listener) Untrack(conn net.Conn) {
+	l.mu.Lock()
+	delete(l.conns, conn)
+	l.mu.Unlock()
+	conn.Close()
+}
+
+// Close closes the socket and the tracked connections
+func (l *Listener) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	done := l.doneLocked()
+	select {
+	case <-done:
+		return
+	default:
+	}
+	close(done)
+	if l.stream != nil {
+		l.stream.Close()
+	}
+	if l.packets != nil {
+		l.packets.Close()
+	}
+	for conn := range l.conns {
+		conn.Close()
+	}
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerClose(t *testing.T) {
+	stream, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var l Listener
+	l.Listen(stream)
+	assert.Equal(t, stream.Addr(), l.Addr())
+	assert.False(t, l.Stopping())
+
+	client, err := net.Dial("tcp", stream.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	conn, err := stream.Accept()
+	require.NoError(t, err)
+	assert.True(t, l.Track(conn, 1))
+	assert.False(t, l.Track(conn, 1), "max connections are tracked")
+
+	l.Close()
+	l.Close()
+	assert.True(t, l.Stopping())
+	select {
+	case <-l.Done():
+	default:
+		t.Fatal("Done is not closed")
+	}
+	_, err = stream.Accept()
+	assert.Error(t, err)
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.False(t, l.Track(conn, 0), "the listener is closed")
+}
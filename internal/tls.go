@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// GetServerTLSConfig returns the TLS config of a listener from its
+// certificate and key files, or nil if no certificate is set. Clients must
+// present a certificate signed by one of allowedCAs, if there are any.
+func GetServerTLSConfig(
+	certFile string,
+	keyFile string,
+	allowedCAs []string,
+) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		if len(allowedCAs) > 0 {
+			return nil, fmt.Errorf("tls_allowed_cacerts needs tls_cert and tls_key")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("Could not load TLS certificate %s: %s",
+			certFile, err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	if len(allowedCAs) > 0 {
+		pool := x509.NewCertPool()
+		for _, path := range allowedCAs {
+			pem, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("Could not read CA certificate %s: %s",
+					path, err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("No certificates found in %s", path)
+			}
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// GetClientTLSConfig returns the TLS config of a client from the CA
+// certificate servers must be signed by, and the certificate and key it
+// presents, or nil if none are set and insecureSkipVerify is false.
+func GetClientTLSConfig(
+	caFile string,
+	certFile string,
+	keyFile string,
+	insecureSkipVerify bool,
+) (*tls.Config, error) {
+	if caFile == "" && certFile == "" && keyFile == "" && !insecureSkipVerify {
+		return nil, nil
+	}
+	config := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read CA certificate %s: %s",
+				caFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found in %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Could not load TLS certificate %s: %s",
+				certFile, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
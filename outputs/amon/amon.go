@@ -4,12 +4,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
 
 	"github.com/influxdb/influxdb/client/v2"
 	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/outputs"
 )
 
@@ -19,6 +19,7 @@ type Amon struct {
 	Timeout      internal.Duration
 
 	client *http.Client
+	log    *logger.Logger
 }
 
 var sampleConfig = `
@@ -69,7 +70,7 @@ func (a *Amon) Write(points []*client.Point) error {
 			tempSeries[acceptablePoints] = metric
 			acceptablePoints += 1
 		} else {
-			log.Printf("unable to build Metric for %s, skipping\n", pt.Name())
+			a.log.Warnf("unable to build Metric for %s, skipping", pt.Name())
 		}
 	}
 	ts.Series = make([]*Metric, acceptablePoints)
@@ -101,6 +102,10 @@ func (a *Amon) SampleConfig() string {
 	return sampleConfig
 }
 
+func (a *Amon) SetLogger(log *logger.Logger) {
+	a.log = log
+}
+
 func (a *Amon) Description() string {
 	return "Configuration for Amon Server to send metrics to."
 }
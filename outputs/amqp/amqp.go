@@ -3,11 +3,11 @@ package amqp
 import (
 	"bytes"
 	"fmt"
-	"log"
 	"sync"
 	"time"
 
 	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/outputs"
 	"github.com/streadway/amqp"
 )
@@ -29,6 +29,7 @@ type AMQP struct {
 	channel *amqp.Channel
 	sync.Mutex
 	headers amqp.Table
+	log     *logger.Logger
 }
 
 const (
@@ -87,10 +88,10 @@ func (q *AMQP) Connect() error {
 	}
 	q.channel = channel
 	go func() {
-		log.Printf("Closing: %s", <-connection.NotifyClose(make(chan *amqp.Error)))
-		log.Printf("Trying to reconnect")
+		q.log.Warnf("Closing: %s", <-connection.NotifyClose(make(chan *amqp.Error)))
+		q.log.Infof("Trying to reconnect")
 		for err := q.Connect(); err != nil; err = q.Connect() {
-			log.Println(err)
+			q.log.Errorf("%s", err)
 			time.Sleep(10 * time.Second)
 		}
 
@@ -106,6 +107,10 @@ func (q *AMQP) SampleConfig() string {
 	return sampleConfig
 }
 
+func (q *AMQP) SetLogger(log *logger.Logger) {
+	q.log = log
+}
+
 func (q *AMQP) Description() string {
 	return "Configuration for the AMQP server to send metrics to"
 }
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"net/url"
 	"sort"
@@ -12,6 +11,7 @@ import (
 
 	"github.com/influxdb/influxdb/client/v2"
 	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/outputs"
 )
 
@@ -21,6 +21,7 @@ type Datadog struct {
 
 	apiUrl string
 	client *http.Client
+	log    *logger.Logger
 }
 
 var sampleConfig = `
@@ -80,7 +81,7 @@ func (d *Datadog) Write(points []*client.Point) error {
 			tempSeries[acceptablePoints] = metric
 			acceptablePoints += 1
 		} else {
-			log.Printf("unable to build Metric for %s, skipping\n", pt.Name())
+			d.log.Warnf("unable to build Metric for %s, skipping", pt.Name())
 		}
 	}
 	ts.Series = make([]*Metric, acceptablePoints)
@@ -112,6 +113,10 @@ func (d *Datadog) SampleConfig() string {
 	return sampleConfig
 }
 
+func (d *Datadog) SetLogger(log *logger.Logger) {
+	d.log = log
+}
+
 func (d *Datadog) Description() string {
 	return "Configuration for DataDog API to send metrics to."
 }
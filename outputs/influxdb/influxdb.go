@@ -3,13 +3,13 @@ package influxdb
 import (
 	"errors"
 	"fmt"
-	"log"
 	"math/rand"
 	"net/url"
 	"strings"
 
 	"github.com/influxdb/influxdb/client/v2"
 	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/outputs"
 )
 
@@ -26,6 +26,7 @@ type InfluxDB struct {
 	UDPPayload int `toml:"udp_payload"`
 
 	conns []client.Client
+	log   *logger.Logger
 }
 
 var sampleConfig = `
@@ -102,7 +103,7 @@ func (i *InfluxDB) Connect() error {
 			})
 
 			if e != nil {
-				log.Println("Database creation failed: " + e.Error())
+				i.log.Errorf("Database creation failed: %s", e.Error())
 			}
 
 			conns = append(conns, c)
@@ -122,6 +123,10 @@ func (i *InfluxDB) SampleConfig() string {
 	return sampleConfig
 }
 
+func (i *InfluxDB) SetLogger(log *logger.Logger) {
+	i.log = log
+}
+
 func (i *InfluxDB) Description() string {
 	return "Configuration for influxdb server to send metrics to"
 }
@@ -144,7 +149,7 @@ func (i *InfluxDB) Write(points []*client.Point) error {
 	p := rand.Perm(len(i.conns))
 	for _, n := range p {
 		if e := i.conns[n].Write(bp); e != nil {
-			log.Println("ERROR: " + e.Error())
+			i.log.Errorf("%s", e.Error())
 		} else {
 			err = nil
 			break
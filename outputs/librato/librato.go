@@ -4,11 +4,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 
 	"github.com/influxdb/influxdb/client/v2"
 	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/outputs"
 )
 
@@ -20,6 +20,7 @@ type Librato struct {
 
 	apiUrl string
 	client *http.Client
+	log    *logger.Logger
 }
 
 var sampleConfig = `
@@ -81,7 +82,7 @@ func (l *Librato) Write(points []*client.Point) error {
 			tempGauges[acceptablePoints] = gauge
 			acceptablePoints += 1
 		} else {
-			log.Printf("unable to build Gauge for %s, skipping\n", pt.Name())
+			l.log.Warnf("unable to build Gauge for %s, skipping", pt.Name())
 		}
 	}
 	metrics.Gauges = make([]*Gauge, acceptablePoints)
@@ -114,6 +115,10 @@ func (l *Librato) SampleConfig() string {
 	return sampleConfig
 }
 
+func (l *Librato) SetLogger(log *logger.Logger) {
+	l.log = log
+}
+
 func (l *Librato) Description() string {
 	return "Configuration for Librato API to send metrics to."
 }
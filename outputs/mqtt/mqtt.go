@@ -1,11 +1,7 @@
 package mqtt
 
 import (
-	"crypto/rand"
-	"crypto/tls"
-	"crypto/x509"
 	"fmt"
-	"io/ioutil"
 	"strings"
 	"sync"
 
@@ -27,6 +23,12 @@ type MQTT struct {
 	Timeout     internal.Duration
 	TopicPrefix string
 
+	// TLS options, the scheme is ssl when any is set
+	TLSCA              string `toml:"tls_ca"`
+	TLSCert            string `toml:"tls_cert"`
+	TLSKey             string `toml:"tls_key"`
+	InsecureSkipVerify bool
+
 	Client *paho.Client
 	Opts   *paho.ClientOptions
 	sync.Mutex
@@ -43,6 +45,14 @@ var sampleConfig = `
   # username and password to connect MQTT server.
   # username = "telegraf"
   # password = "metricsmetricsmetricsmetrics"
+
+  # connect over TLS, verifying the server with the CA certificate, and
+  # presenting a client certificate
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  # connect over TLS without verifying the server
+  # insecure_skip_verify = false
 `
 
 func (m *MQTT) Connect() error {
@@ -124,22 +134,19 @@ func (m *MQTT) publish(topic, body string) error {
 func (m *MQTT) CreateOpts() (*paho.ClientOptions, error) {
 	opts := paho.NewClientOptions()
 
-	clientId := getRandomClientId()
+	clientId := ClientIdPrefix + "-" + internal.RandomString(MaxClientIdLen)
 	opts.SetClientID(clientId)
 
-	TLSConfig := &tls.Config{InsecureSkipVerify: false}
-	ca := "" // TODO
+	tlsConfig, err := internal.GetClientTLSConfig(
+		m.TLSCA, m.TLSCert, m.TLSKey, m.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
 	scheme := "tcp"
-	if ca != "" {
+	if tlsConfig != nil {
 		scheme = "ssl"
-		certPool, err := getCertPool(ca)
-		if err != nil {
-			return nil, err
-		}
-		TLSConfig.RootCAs = certPool
+		opts.SetTLSConfig(tlsConfig)
 	}
-	TLSConfig.InsecureSkipVerify = true // TODO
-	opts.SetTLSConfig(TLSConfig)
 
 	user := m.Username
 	if user == "" {
@@ -162,27 +169,6 @@ func (m *MQTT) CreateOpts() (*paho.ClientOptions, error) {
 	return opts, nil
 }
 
-func getRandomClientId() string {
-	const alphanum = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
-	var bytes = make([]byte, MaxClientIdLen)
-	rand.Read(bytes)
-	for i, b := range bytes {
-		bytes[i] = alphanum[b%byte(len(alphanum))]
-	}
-	return ClientIdPrefix + "-" + string(bytes)
-}
-
-func getCertPool(pemPath string) (*x509.CertPool, error) {
-	certs := x509.NewCertPool()
-
-	pemData, err := ioutil.ReadFile(pemPath)
-	if err != nil {
-		return nil, err
-	}
-	certs.AppendCertsFromPEM(pemData)
-	return certs, nil
-}
-
 func init() {
 	outputs.Add("mqtt", func() outputs.Output {
 		return &MQTT{}
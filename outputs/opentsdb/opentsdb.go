@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/outputs"
 )
 
@@ -19,6 +20,8 @@ type OpenTSDB struct {
 	Port int
 
 	Debug bool
+
+	log *logger.Logger
 }
 
 var sampleConfig = `
@@ -78,7 +81,7 @@ func (o *OpenTSDB) Write(points []*client.Point) error {
 
 		metricValue, buildError := buildValue(pt)
 		if buildError != nil {
-			fmt.Printf("OpenTSDB: %s\n", buildError.Error())
+			o.log.Errorf("%s", buildError.Error())
 			continue
 		}
 		metric.Value = metricValue
@@ -88,7 +91,7 @@ func (o *OpenTSDB) Write(points []*client.Point) error {
 
 		messageLine := fmt.Sprintf("put %s %v %s %s\n", metric.Metric, metric.Timestamp, metric.Value, metric.Tags)
 		if o.Debug {
-			fmt.Print(messageLine)
+			o.log.Infof("%s", strings.TrimSuffix(messageLine, "\n"))
 		}
 		_, err := connection.Write([]byte(messageLine))
 		if err != nil {
@@ -139,6 +142,10 @@ func FloatToString(input_num float64) string {
 	return strconv.FormatFloat(input_num, 'f', 6, 64)
 }
 
+func (o *OpenTSDB) SetLogger(log *logger.Logger) {
+	o.log = log
+}
+
 func (o *OpenTSDB) SampleConfig() string {
 	return sampleConfig
 }
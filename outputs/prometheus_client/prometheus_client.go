@@ -2,10 +2,10 @@ package prometheus_client
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 
 	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/outputs"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -13,6 +13,7 @@ import (
 type PrometheusClient struct {
 	Listen  string
 	metrics map[string]*prometheus.UntypedVec
+	log     *logger.Logger
 }
 
 var sampleConfig = `
@@ -54,6 +55,10 @@ func (p *PrometheusClient) SampleConfig() string {
 	return sampleConfig
 }
 
+func (p *PrometheusClient) SetLogger(log *logger.Logger) {
+	p.log = log
+}
+
 func (p *PrometheusClient) Description() string {
 	return "Configuration for the Prometheus client to spawn"
 }
@@ -92,13 +97,12 @@ func (p *PrometheusClient) Write(points []*client.Point) error {
 		for _, val := range point.Fields() {
 			switch val := val.(type) {
 			default:
-				log.Printf("Prometheus output, unsupported type. key: %s, type: %T\n",
+				p.log.Warnf("Unsupported type. key: %s, type: %T",
 					key, val)
 			case int64:
 				m, err := p.metrics[key].GetMetricWith(l)
 				if err != nil {
-					log.Printf("ERROR Getting metric in Prometheus output, "+
-						"key: %s, labels: %v,\nerr: %s\n",
+					p.log.Errorf("Getting metric, key: %s, labels: %v, err: %s",
 						key, l, err.Error())
 					continue
 				}
@@ -106,8 +110,7 @@ func (p *PrometheusClient) Write(points []*client.Point) error {
 			case float64:
 				m, err := p.metrics[key].GetMetricWith(l)
 				if err != nil {
-					log.Printf("ERROR Getting metric in Prometheus output, "+
-						"key: %s, labels: %v,\nerr: %s\n",
+					p.log.Errorf("Getting metric, key: %s, labels: %v, err: %s",
 						key, l, err.Error())
 					continue
 				}
@@ -1,6 +1,8 @@
 package outputs
 
 import (
+	"github.com/influxdb/telegraf/internal/logger"
+
 	"github.com/influxdb/influxdb/client/v2"
 )
 
@@ -34,6 +36,12 @@ type ServiceOutput interface {
 	Stop()
 }
 
+// LoggerSetter is implemented by outputs that log. The agent hands each
+// output instance a logger that tags every line with the output's name.
+type LoggerSetter interface {
+	SetLogger(*logger.Logger)
+}
+
 type Creator func() Output
 
 var Outputs = map[string]Creator{}
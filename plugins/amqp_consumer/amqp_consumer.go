@@ -0,0 +1,413 @@
+package amqp_consumer
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/internal/parsers"
+	"github.com/influxdb/telegraf/internal/service"
+	"github.com/influxdb/telegraf/plugins"
+	"github.com/streadway/amqp"
+)
+
+const sampleConfig = `
+  # AMQP url
+  url = "amqp://localhost:5672/influxdb"
+
+  # exchange to bind the queue to, declared if it doesn't exist. Empty to
+  # consume a queue without binding it.
+  exchange = "telegraf"
+  # type of the exchange: direct, fanout, topic or headers. The amqp output
+  # publishes to a durable topic exchange.
+  exchange_type = "topic"
+  exchange_durable = true
+
+  # queue to consume, declared if it doesn't exist
+  queue = "telegraf"
+  queue_durable = true
+  # routing key the queue is bound to the exchange with, "#" for all the
+  # messages of a topic exchange
+  binding_key = "#"
+
+  # maximum number of messages the broker sends before they are
+  # acknowledged. Messages are acknowledged once added on the interval, so
+  # this bounds the messages consumed per interval.
+  prefetch_count = 1000
+  # how long to wait before reconnecting when the channel is closed
+  reconnect_delay = "10s"
+
+  # connect over TLS, verifying the server with the CA certificate, and
+  # presenting a client certificate
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  # connect over TLS without verifying the server
+  # insecure_skip_verify = false
+
+  # format of the messages, "influx" or "json"
+  data_format = "influx"
+  # measurement name and tag keys of json metrics
+  # metric_name = "amqp_consumer"
+  # tag_keys = ["host"]
+
+  # maximum number of points to buffer between collection intervals. Once
+  # it is reached, consuming pauses until the points are gathered.
+  point_buffer = 100000
+`
+
+type AMQPConsumer struct {
+	URL             string            `doc:"AMQP url"`
+	Exchange        string            `doc:"exchange to bind the queue to, declared if it doesn't exist"`
+	ExchangeType    string            `doc:"type of the exchange: direct, fanout, topic or headers"`
+	ExchangeDurable bool              `doc:"declare the exchange durable"`
+	Queue           string            `doc:"queue to consume, declared if it doesn't exist"`
+	QueueDurable    bool              `doc:"declare the queue durable"`
+	BindingKey      string            `doc:"routing key the queue is bound to the exchange with"`
+	PrefetchCount   int               `doc:"maximum number of unacknowledged messages"`
+	ReconnectDelay  internal.Duration `doc:"how long to wait before reconnecting"`
+
+	TLSCA              string `toml:"tls_ca" doc:"CA certificate the broker must be signed by"`
+	TLSCert            string `toml:"tls_cert" doc:"client certificate to present"`
+	TLSKey             string `toml:"tls_key" doc:"key of the client certificate"`
+	InsecureSkipVerify bool   `doc:"connect over TLS without verifying the broker"`
+
+	DataFormat string   `doc:"format of the messages: influx or json"`
+	MetricName string   `doc:"measurement name of json metrics"`
+	TagKeys    []string `doc:"json keys to use as tags"`
+
+	PointBuffer int `doc:"maximum number of points to buffer between collection intervals"`
+
+	sync.Mutex
+	// dial connects to the broker, it is replaced by tests
+	dial      func(url string, config *tls.Config) (connection, error)
+	tlsConfig *tls.Config
+	conn      connection
+	parser    parsers.Parser
+	buffer    service.Buffer
+	stopping  bool
+	done      chan struct{}
+	wg        sync.WaitGroup
+	// gathered is closed and replaced by every Gather, to resume consuming
+	// once the buffer is full
+	gathered chan struct{}
+	paused   bool
+
+	// acker and lastTag are those of the last delivery buffered, which is
+	// acknowledged with the ones before it on the next Gather
+	acker   amqp.Acknowledger
+	lastTag uint64
+
+	log *logger.Logger
+}
+
+// connection is the part of *amqp.Connection the plugin uses
+type connection interface {
+	Channel() (channel, error)
+	Close() error
+}
+
+// channel is the part of *amqp.Channel the plugin uses
+type channel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+}
+
+// amqpConnection adapts *amqp.Connection to connection
+type amqpConnection struct {
+	*amqp.Connection
+}
+
+func (c amqpConnection) Channel() (channel, error) {
+	return c.Connection.Channel()
+}
+
+func dial(url string, config *tls.Config) (connection, error) {
+	conn, err := amqp.DialTLS(url, config)
+	if err != nil {
+		return nil, err
+	}
+	return amqpConnection{conn}, nil
+}
+
+func NewAMQPConsumer() *AMQPConsumer {
+	return &AMQPConsumer{
+		URL:             "amqp://localhost:5672/influxdb",
+		Exchange:        "telegraf",
+		ExchangeType:    "topic",
+		ExchangeDurable: true,
+		Queue:           "telegraf",
+		QueueDurable:    true,
+		BindingKey:      "#",
+		PrefetchCount:   1000,
+		ReconnectDelay:  internal.Duration{Duration: 10 * time.Second},
+		MetricName:      "amqp_consumer",
+		PointBuffer:     service.DefaultPointBuffer,
+		dial:            dial,
+	}
+}
+
+func (a *AMQPConsumer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *AMQPConsumer) Description() string {
+	return "Read metrics from an AMQP queue, such as the amqp output writes"
+}
+
+func (a *AMQPConsumer) SetLogger(log *logger.Logger) {
+	a.log = log
+}
+
+func (a *AMQPConsumer) Start() error {
+	a.buffer.SetMax(a.PointBuffer)
+	var err error
+	a.parser, err = parsers.NewParser(&parsers.Config{
+		DataFormat: a.DataFormat,
+		MetricName: a.MetricName,
+		TagKeys:    a.TagKeys,
+	})
+	if err != nil {
+		return err
+	}
+	if a.Queue == "" {
+		return fmt.Errorf("amqp_consumer: no queue")
+	}
+	a.tlsConfig, err = internal.GetClientTLSConfig(
+		a.TLSCA, a.TLSCert, a.TLSKey, a.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := a.connect()
+	if err != nil {
+		return err
+	}
+	a.done = make(chan struct{})
+	a.gathered = make(chan struct{})
+	a.wg.Add(1)
+	go a.consume(deliveries)
+	a.log.Infof("Consuming queue %s of %s", a.Queue, a.Exchange)
+	return nil
+}
+
+// connect connects to the broker, declares the exchange and the queue, and
+// starts consuming the queue
+func (a *AMQPConsumer) connect() (<-chan amqp.Delivery, error) {
+	conn, err := a.dial(a.URL, a.tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("amqp_consumer: could not connect: %s", err)
+	}
+	deliveries, err := a.setup(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("amqp_consumer: %s", err)
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	if a.stopping {
+		conn.Close()
+		return nil, fmt.Errorf("amqp_consumer: stopping")
+	}
+	if a.conn != nil {
+		a.conn.Close()
+	}
+	a.conn = conn
+	return deliveries, nil
+}
+
+func (a *AMQPConsumer) setup(conn connection) (<-chan amqp.Delivery, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("could not open a channel: %s", err)
+	}
+
+	if a.Exchange != "" {
+		err = ch.ExchangeDeclare(
+			a.Exchange,        // name
+			a.ExchangeType,    // type
+			a.ExchangeDurable, // durable
+			false,             // delete when unused
+			false,             // internal
+			false,             // no-wait
+			nil,               // arguments
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not declare exchange %s: %s",
+				a.Exchange, err)
+		}
+	}
+
+	_, err = ch.QueueDeclare(
+		a.Queue,        // name
+		a.QueueDurable, // durable
+		false,          // delete when unused
+		false,          // exclusive
+		false,          // no-wait
+		nil,            // arguments
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not declare queue %s: %s", a.Queue, err)
+	}
+
+	if a.Exchange != "" {
+		err = ch.QueueBind(a.Queue, a.BindingKey, a.Exchange, false, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not bind queue %s to exchange %s: %s",
+				a.Queue, a.Exchange, err)
+		}
+	}
+
+	if err := ch.Qos(a.PrefetchCount, 0, false); err != nil {
+		return nil, fmt.Errorf("could not set prefetch count: %s", err)
+	}
+
+	deliveries, err := ch.Consume(
+		a.Queue, // queue
+		"",      // consumer tag, generated by the broker
+		false,   // auto-ack
+		false,   // exclusive
+		false,   // no-local
+		false,   // no-wait
+		nil,     // arguments
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not consume queue %s: %s", a.Queue, err)
+	}
+	return deliveries, nil
+}
+
+// consume handles deliveries until Stop, reconnecting whenever the channel
+// is closed, which closes its deliveries
+func (a *AMQPConsumer) consume(deliveries <-chan amqp.Delivery) {
+	defer a.wg.Done()
+	for {
+		for d := range deliveries {
+			a.onDelivery(d)
+		}
+		select {
+		case <-a.done:
+			return
+		default:
+		}
+
+		a.log.Warnf("Channel closed, reconnecting in %s", a.ReconnectDelay.Duration)
+		for {
+			select {
+			case <-a.done:
+				return
+			case <-time.After(a.ReconnectDelay.Duration):
+			}
+
+			var err error
+			deliveries, err = a.connect()
+			if err == nil {
+				a.log.Infof("Reconnected")
+				break
+			}
+			a.log.Errorf("%s", err)
+		}
+	}
+}
+
+// onDelivery parses a message, and buffers its points for the next Gather,
+// which acknowledges it. Messages without any valid point are rejected, so
+// that they aren't delivered again. While the buffer is full, consuming
+// stops until the next Gather, so that only messages whose points were kept
+// are acknowledged and the broker holds on to the others.
+func (a *AMQPConsumer) onDelivery(d amqp.Delivery) {
+	parser := a.parser
+	// the amqp output sends line protocol with a precision header
+	if precision, ok := d.Headers["precision"].(string); ok &&
+		(a.DataFormat == "" || a.DataFormat == "influx") {
+		parser = &parsers.InfluxParser{Precision: precision}
+	}
+
+	points, err := parser.Parse(d.Body)
+	if err != nil {
+		a.log.Errorf("Could not parse message: %s, error: %s",
+			string(d.Body), err)
+		if len(points) == 0 {
+			if err := d.Reject(false); err != nil {
+				a.log.Errorf("Could not reject message: %s", err)
+			}
+			return
+		}
+	}
+
+	for {
+		a.Lock()
+		added := a.buffer.TryAdd(points...)
+		if !added && a.buffer.Len() == 0 {
+			// a message with more points than the buffer holds would never
+			// fit, the points that don't are dropped
+			a.buffer.Add(points...)
+			added = true
+		}
+		if added {
+			a.acker, a.lastTag = d.Acknowledger, d.DeliveryTag
+			a.Unlock()
+			return
+		}
+		if !a.paused {
+			a.log.Warnf("Buffer is full, pausing consuming until the next " +
+				"Gather, you may want to increase point_buffer")
+			a.paused = true
+		}
+		gathered := a.gathered
+		a.Unlock()
+
+		select {
+		case <-gathered:
+		case <-a.done:
+			// the message is delivered again once the connection is closed
+			return
+		}
+	}
+}
+
+func (a *AMQPConsumer) Stop() {
+	a.Lock()
+	a.stopping = true
+	close(a.done)
+	if a.conn != nil {
+		a.conn.Close()
+	}
+	a.Unlock()
+	a.wg.Wait()
+}
+
+// Gather adds the metrics received since the last Gather, and then
+// acknowledges their messages
+func (a *AMQPConsumer) Gather(acc plugins.Accumulator) error {
+	a.Lock()
+	defer a.Unlock()
+
+	a.buffer.Gather(acc, a.log)
+	if a.gathered != nil {
+		close(a.gathered)
+		a.gathered = make(chan struct{})
+	}
+	a.paused = false
+	if a.acker == nil {
+		return nil
+	}
+	if err := a.acker.Ack(a.lastTag, true); err != nil {
+		a.log.Errorf("Could not acknowledge messages: %s", err)
+	}
+	a.acker = nil
+	return nil
+}
+
+func init() {
+	plugins.Add("amqp_consumer", func() plugins.Plugin {
+		return NewAMQPConsumer()
+	})
+}
@@ -0,0 +1,343 @@
+package amqp_consumer
+
+import (
+	"crypto/tls"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/streadway/amqp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// broker is an in-process AMQP stand-in. It records what the plugin
+// declares, delivers the messages published to it to the plugin's
+// consumer, and records their acknowledgements.
+type broker struct {
+	sync.Mutex
+	dials     int
+	failDials int
+	exchanges map[string]string
+	queues    map[string]bool
+	bindings  []string
+	prefetch  int
+	tag       uint64
+	acks      []uint64
+	rejects   []uint64
+
+	// consumer is the connection consuming the queue, nil when its
+	// channel is closed
+	consumer *fakeConnection
+}
+
+func newBroker() *broker {
+	return &broker{
+		exchanges: make(map[string]string),
+		queues:    make(map[string]bool),
+	}
+}
+
+func (b *broker) dial(url string, config *tls.Config) (connection, error) {
+	b.Lock()
+	defer b.Unlock()
+	b.dials++
+	if b.failDials > 0 {
+		b.failDials--
+		return nil, errors.New("connection refused")
+	}
+	return &fakeConnection{b: b}, nil
+}
+
+// publish delivers a message to the consumer
+func (b *broker) publish(t *testing.T, body string, headers amqp.Table) {
+	b.Lock()
+	defer b.Unlock()
+	require.NotNil(t, b.consumer, "no consumer")
+	b.tag++
+	b.consumer.deliveries <- amqp.Delivery{
+		Acknowledger: b,
+		DeliveryTag:  b.tag,
+		Headers:      headers,
+		Body:         []byte(body),
+	}
+}
+
+// closeChannel closes the consumer's channel, as the broker does on a
+// channel exception
+func (b *broker) closeChannel() {
+	b.Lock()
+	defer b.Unlock()
+	if b.consumer != nil {
+		b.consumer.closeChannel()
+	}
+}
+
+// waitFor waits for cond to hold, for up to a second
+func (b *broker) waitFor(t *testing.T, cond func() bool) {
+	for i := 0; i < 100; i++ {
+		b.Lock()
+		ok := cond()
+		b.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for the broker")
+}
+
+func (b *broker) Ack(tag uint64, multiple bool) error {
+	if !multiple {
+		return errors.New("unexpected single ack")
+	}
+	b.Lock()
+	defer b.Unlock()
+	b.acks = append(b.acks, tag)
+	return nil
+}
+
+func (b *broker) Nack(tag uint64, multiple bool, requeue bool) error {
+	return errors.New("unexpected nack")
+}
+
+func (b *broker) Reject(tag uint64, requeue bool) error {
+	b.Lock()
+	defer b.Unlock()
+	b.rejects = append(b.rejects, tag)
+	return nil
+}
+
+// fakeConnection is a connection to the broker with a single channel
+type fakeConnection struct {
+	b          *broker
+	deliveries chan amqp.Delivery
+}
+
+func (c *fakeConnection) Channel() (channel, error) {
+	return &fakeChannel{c.b, c}, nil
+}
+
+func (c *fakeConnection) Close() error {
+	c.b.Lock()
+	defer c.b.Unlock()
+	c.closeChannel()
+	return nil
+}
+
+// closeChannel closes the deliveries of the connection's channel, with the
+// broker locked
+func (c *fakeConnection) closeChannel() {
+	if c.deliveries != nil {
+		close(c.deliveries)
+		c.deliveries = nil
+	}
+	if c.b.consumer == c {
+		c.b.consumer = nil
+	}
+}
+
+type fakeChannel struct {
+	b    *broker
+	conn *fakeConnection
+}
+
+func (c *fakeChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	c.b.Lock()
+	defer c.b.Unlock()
+	c.b.exchanges[name] = kind
+	return nil
+}
+
+func (c *fakeChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	c.b.Lock()
+	defer c.b.Unlock()
+	c.b.queues[name] = durable
+	return amqp.Queue{Name: name}, nil
+}
+
+func (c *fakeChannel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	c.b.Lock()
+	defer c.b.Unlock()
+	c.b.bindings = append(c.b.bindings, name+" "+key+" "+exchange)
+	return nil
+}
+
+func (c *fakeChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	c.b.Lock()
+	defer c.b.Unlock()
+	c.b.prefetch = prefetchCount
+	return nil
+}
+
+func (c *fakeChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	c.b.Lock()
+	defer c.b.Unlock()
+	// buffered, so that publish, which holds the broker's lock, doesn't
+	// wait for the plugin, whose acknowledgements need the lock
+	c.conn.deliveries = make(chan amqp.Delivery, 100)
+	c.b.consumer = c.conn
+	return c.conn.deliveries, nil
+}
+
+// newConsumer returns a consumer connecting to b
+func newConsumer(b *broker) *AMQPConsumer {
+	a := NewAMQPConsumer()
+	a.dial = b.dial
+	a.ReconnectDelay.Duration = 10 * time.Millisecond
+	return a
+}
+
+func TestConsume(t *testing.T) {
+	b := newBroker()
+	a := newConsumer(b)
+	defer testutil.StartService(t, a)()
+
+	assert.Equal(t, map[string]string{"telegraf": "topic"}, b.exchanges)
+	assert.Equal(t, map[string]bool{"telegraf": true}, b.queues)
+	assert.Equal(t, []string{"telegraf # telegraf"}, b.bindings)
+	assert.Equal(t, 1000, b.prefetch)
+
+	// as the amqp output publishes
+	b.publish(t, "cpu,host=a usage=1.5 1445000000\nmem free=10i 1445000000",
+		amqp.Table{"precision": "s", "database": "telegraf"})
+	b.publish(t, "bad", nil)
+	b.publish(t, "disk used=1i", nil)
+
+	// messages are only acknowledged once gathered
+	b.waitFor(t, func() bool { return len(b.rejects) == 1 })
+	assert.Equal(t, []uint64{2}, b.rejects)
+	b.Lock()
+	assert.Empty(t, b.acks)
+	b.Unlock()
+
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, a, &acc, 3)
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("cpu",
+		map[string]interface{}{"usage": 1.5}, map[string]string{"host": "a"}))
+	assert.Equal(t, time.Unix(1445000000, 0).UTC(), acc.Points[0].Time.UTC())
+
+	b.Lock()
+	require.NotEmpty(t, b.acks)
+	assert.Equal(t, uint64(3), b.acks[len(b.acks)-1])
+	b.Unlock()
+
+	// nothing is left to acknowledge
+	n := len(b.acks)
+	require.NoError(t, a.Gather(&acc))
+	b.Lock()
+	assert.Len(t, b.acks, n)
+	b.Unlock()
+}
+
+func TestConsumeJSON(t *testing.T) {
+	b := newBroker()
+	a := newConsumer(b)
+	a.Exchange = ""
+	a.DataFormat = "json"
+	a.MetricName = "app"
+	defer testutil.StartService(t, a)()
+
+	assert.Empty(t, b.exchanges)
+	assert.Empty(t, b.bindings)
+
+	// the precision header only applies to line protocol
+	b.publish(t, `{"requests": 10}`, amqp.Table{"precision": "s"})
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, a, &acc, 1)
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("app",
+		map[string]interface{}{"requests": float64(10)}, nil))
+}
+
+func TestConsumeBufferFull(t *testing.T) {
+	b := newBroker()
+	a := newConsumer(b)
+	a.PointBuffer = 2
+	defer testutil.StartService(t, a)()
+
+	b.publish(t, "cpu usage=1\ncpu usage=2", nil)
+	b.publish(t, "cpu usage=3", nil)
+	b.waitFor(t, func() bool { return a.buffer.Len() == 2 })
+
+	// the message that doesn't fit waits for the next Gather, and isn't
+	// acknowledged before its points are kept
+	var acc testutil.Accumulator
+	require.NoError(t, a.Gather(&acc))
+	assert.Len(t, acc.Points, 2)
+	b.Lock()
+	assert.Equal(t, []uint64{1}, b.acks)
+	b.Unlock()
+
+	testutil.GatherUntil(t, a, &acc, 3)
+	require.NoError(t, a.Gather(&acc))
+	b.Lock()
+	assert.Equal(t, uint64(2), b.acks[len(b.acks)-1])
+	b.Unlock()
+
+	// a message with more points than the buffer holds is cut short
+	b.publish(t, "cpu usage=4\ncpu usage=5\ncpu usage=6", nil)
+	testutil.GatherUntil(t, a, &acc, 5)
+	require.NoError(t, a.Gather(&acc))
+	assert.Len(t, acc.Points, 5)
+	b.Lock()
+	assert.Equal(t, uint64(3), b.acks[len(b.acks)-1])
+	b.Unlock()
+}
+
+func TestReconnect(t *testing.T) {
+	b := newBroker()
+	a := newConsumer(b)
+	defer testutil.StartService(t, a)()
+
+	b.Lock()
+	b.failDials = 2
+	b.Unlock()
+	b.closeChannel()
+	b.waitFor(t, func() bool { return b.consumer != nil })
+	assert.Equal(t, 4, b.dials)
+
+	b.publish(t, "cpu usage=1.5", nil)
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, a, &acc, 1)
+}
+
+func TestStopWhileReconnecting(t *testing.T) {
+	b := newBroker()
+	a := newConsumer(b)
+	testutil.StartService(t, a)
+
+	b.Lock()
+	b.failDials = 1000
+	b.Unlock()
+	b.closeChannel()
+	b.waitFor(t, func() bool { return b.dials > 2 })
+
+	stopped := make(chan struct{})
+	go func() {
+		a.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop didn't return")
+	}
+}
+
+func TestStartErrors(t *testing.T) {
+	b := newBroker()
+	b.failDials = 1
+	a := newConsumer(b)
+	assert.Error(t, a.Start())
+
+	a = newConsumer(b)
+	a.Queue = ""
+	assert.Error(t, a.Start())
+
+	a = newConsumer(b)
+	a.DataFormat = "xml"
+	assert.Error(t, a.Start())
+}
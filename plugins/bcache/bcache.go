@@ -24,7 +24,7 @@ var sampleConfig = `
   # By default, telegraf gather stats for all bcache devices
   # Setting devices will restrict the stats to the specified
   # bcache devices.
-  # bcacheDevs = ["bcache0", ...]
+  # bcacheDevs = ["bcache0"]
 `
 
 func (b *Bcache) SampleConfig() string {
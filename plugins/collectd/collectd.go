@@ -0,0 +1,191 @@
+package collectd
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb/models"
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/internal/service"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+const sampleConfig = `
+  # udp address to receive the packets of collectd's network plugin on
+  service_address = ":25826"
+
+  # size of the socket's receive buffer in bytes, 0 for the OS default
+  read_buffer_size = 0
+
+  # maximum number of points to buffer between collection intervals
+  point_buffer = 100000
+
+  # security the packets must have: "none", "sign" or "encrypt". With
+  # "none", signed packets of users in the auth file are verified, and
+  # encrypted packets are decrypted.
+  security_level = "none"
+  # file of "user: password" lines, as collectd's auth file
+  # auth_file = "/etc/collectd/auth_file"
+
+  # types.db files, naming the values of each type
+  typesdb = ["/usr/share/collectd/types.db"]
+`
+
+// maxPacketSize is the maximum size of a UDP packet
+const maxPacketSize = 64 * 1024
+
+type Collectd struct {
+	ServiceAddress string        `doc:"udp address to receive packets on"`
+	ReadBufferSize internal.Size `doc:"size of the socket's receive buffer in bytes, 0 for the OS default"`
+	PointBuffer    int           `doc:"maximum number of points to buffer between collection intervals"`
+	SecurityLevel  string        `doc:"security the packets must have: none, sign or encrypt"`
+	AuthFile       string        `doc:"file of user: password lines"`
+	TypesDB        []string      `toml:"typesdb" doc:"types.db files, naming the values of each type"`
+
+	service.Listener
+	parser *packetParser
+	types  typesDB
+	buffer service.Buffer
+	wg     sync.WaitGroup
+
+	log *logger.Logger
+}
+
+func NewCollectd() *Collectd {
+	return &Collectd{
+		ServiceAddress: ":25826",
+		PointBuffer:    service.DefaultPointBuffer,
+		SecurityLevel:  "none",
+		TypesDB:        []string{"/usr/share/collectd/types.db"},
+	}
+}
+
+func (c *Collectd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Collectd) Description() string {
+	return "Accept metrics sent by collectd's network plugin"
+}
+
+func (c *Collectd) SetLogger(log *logger.Logger) {
+	c.log = log
+}
+
+func (c *Collectd) Start() error {
+	level, err := parseSecurityLevel(c.SecurityLevel)
+	if err != nil {
+		return fmt.Errorf("collectd: %s", err)
+	}
+	c.parser = &packetParser{level: level}
+	if c.AuthFile != "" {
+		c.parser.auth, err = loadAuthFile(c.AuthFile)
+		if err != nil {
+			return fmt.Errorf("collectd: %s", err)
+		}
+	} else if level > securityNone {
+		return fmt.Errorf("collectd: security level %s needs an auth_file",
+			c.SecurityLevel)
+	}
+	c.types, err = loadTypesDB(c.TypesDB)
+	if err != nil {
+		return fmt.Errorf("collectd: %s", err)
+	}
+
+	c.buffer.SetMax(c.PointBuffer)
+	addr, err := net.ResolveUDPAddr("udp", c.ServiceAddress)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	if c.ReadBufferSize.Size > 0 {
+		if err := conn.SetReadBuffer(int(c.ReadBufferSize.Size)); err != nil {
+			c.log.Warnf("Unable to set read buffer size: %s", err)
+		}
+	}
+	c.ListenPacket(conn)
+
+	c.wg.Add(1)
+	go c.read(conn)
+	c.log.Infof("Listening on udp://%s", c.Addr())
+	return nil
+}
+
+func (c *Collectd) read(conn *net.UDPConn) {
+	defer c.wg.Done()
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if !c.Stopping() {
+				c.log.Errorf("Error reading packet: %s", err)
+			}
+			return
+		}
+
+		lists, err := c.parser.parse(buf[:n])
+		if err != nil {
+			c.log.Errorf("Invalid packet from %s: %s", from, err)
+		}
+		c.buffer.Add(c.toPoints(lists)...)
+	}
+}
+
+// toPoints turns value lists into points named after the plugin, tagged
+// with the host, plugin instance, type and type instance, with the values
+// as fields named after the data sources of the type
+func (c *Collectd) toPoints(lists []valueList) []models.Point {
+	var points []models.Point
+	for _, vl := range lists {
+		tags := map[string]string{"type": vl.Type}
+		if vl.Host != "" {
+			tags["host"] = vl.Host
+		}
+		if vl.PluginInstance != "" {
+			tags["instance"] = vl.PluginInstance
+		}
+		if vl.TypeInstance != "" {
+			tags["type_instance"] = vl.TypeInstance
+		}
+
+		fields := make(map[string]interface{}, len(vl.Values))
+		for i, name := range c.types.names(vl.Type, len(vl.Values)) {
+			fields[name] = vl.Values[i]
+		}
+
+		t := vl.Time
+		if t.IsZero() {
+			t = time.Now()
+		}
+		pt, err := models.NewPoint(vl.Plugin, tags, fields, t)
+		if err != nil {
+			c.log.Errorf("Invalid values of %s/%s: %s", vl.Plugin, vl.Type, err)
+			continue
+		}
+		points = append(points, pt)
+	}
+	return points
+}
+
+func (c *Collectd) Stop() {
+	c.Close()
+	c.wg.Wait()
+}
+
+// Gather adds the metrics received since the last Gather
+func (c *Collectd) Gather(acc plugins.Accumulator) error {
+	c.buffer.Gather(acc, c.log)
+	return nil
+}
+
+func init() {
+	plugins.Add("collectd", func() plugins.Plugin {
+		return NewCollectd()
+	})
+}
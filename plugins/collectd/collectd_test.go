@@ -0,0 +1,109 @@
+package collectd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTypesDB = `
+# a few types of collectd's types.db
+cpu        value:DERIVE:0:U
+load       shortterm:GAUGE:0:5000, midterm:GAUGE:0:5000, longterm:GAUGE:0:5000
+if_octets  rx:DERIVE:0:U, tx:DERIVE:0:U
+`
+
+// newCollectd returns a plugin listening on a random port, with the test
+// types.db and an auth file in dir
+func newCollectd(t *testing.T, dir string) *Collectd {
+	typesdb := filepath.Join(dir, "types.db")
+	require.NoError(t, ioutil.WriteFile(typesdb, []byte(testTypesDB), 0644))
+	authFile := filepath.Join(dir, "auth_file")
+	require.NoError(t, ioutil.WriteFile(authFile, []byte("alice: secret\n"), 0600))
+
+	c := NewCollectd()
+	c.ServiceAddress = "127.0.0.1:0"
+	c.TypesDB = []string{typesdb}
+	c.AuthFile = authFile
+	return c
+}
+
+func TestCollectd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "collectd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := newCollectd(t, dir)
+	c.SecurityLevel = "sign"
+	defer testutil.StartService(t, c)()
+
+	conn, err := net.Dial("udp", c.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	now := time.Unix(1445000000, 0)
+	// unsigned, refused
+	_, err = conn.Write(cpuPacket(now).Bytes())
+	require.NoError(t, err)
+	_, err = conn.Write(sign(cpuPacket(now), "alice", "secret"))
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, c, &acc, 3)
+	time.Sleep(50 * time.Millisecond)
+	c.Gather(&acc)
+	require.Len(t, acc.Points, 3)
+
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("cpu",
+		map[string]interface{}{"value": int64(20)},
+		map[string]string{"host": "web1", "instance": "0", "type": "cpu",
+			"type_instance": "user"}))
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("load",
+		map[string]interface{}{
+			"shortterm": 0.5,
+			"midterm":   0.25,
+			"longterm":  0.125,
+		},
+		map[string]string{"host": "web1", "type": "load"}))
+	assert.Equal(t, now.UTC(), acc.Points[0].Time.UTC())
+}
+
+func TestTypesDB(t *testing.T) {
+	db := make(typesDB)
+	require.NoError(t, db.read(strings.NewReader(testTypesDB)))
+	assert.Equal(t, []string{"rx", "tx"}, db.names("if_octets", 2))
+	assert.Equal(t, []string{"value"}, db.names("cpu", 1))
+	// unknown types, and types with another number of values
+	assert.Equal(t, []string{"value"}, db.names("unknown", 1))
+	assert.Equal(t, []string{"value0", "value1"}, db.names("cpu", 2))
+
+	assert.Error(t, db.read(strings.NewReader("load\n")))
+	assert.Error(t, db.read(strings.NewReader("load shortterm:GAUGE\n")))
+}
+
+func TestCollectdBadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "collectd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := newCollectd(t, dir)
+	c.SecurityLevel = "paranoid"
+	assert.Error(t, c.Start())
+
+	c = newCollectd(t, dir)
+	c.SecurityLevel = "encrypt"
+	c.AuthFile = ""
+	assert.Error(t, c.Start())
+
+	c = newCollectd(t, dir)
+	c.TypesDB = []string{filepath.Join(dir, "missing.db")}
+	assert.Error(t, c.Start())
+}
@@ -0,0 +1,310 @@
+package collectd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// part types of the collectd binary network protocol, see
+// https://collectd.org/wiki/index.php/Binary_protocol
+const (
+	partHost           = 0x0000
+	partTime           = 0x0001
+	partPlugin         = 0x0002
+	partPluginInstance = 0x0003
+	partType           = 0x0004
+	partTypeInstance   = 0x0005
+	partValues         = 0x0006
+	partInterval       = 0x0007
+	partTimeHR         = 0x0008
+	partIntervalHR     = 0x0009
+	partMessage        = 0x0100
+	partSeverity       = 0x0101
+	partSignature      = 0x0200
+	partEncryption     = 0x0210
+)
+
+// data source types of values
+const (
+	dsCounter  = 0
+	dsGauge    = 1
+	dsDerive   = 2
+	dsAbsolute = 3
+)
+
+// securityLevel is the security of the parts of a packet, and the minimum
+// security the plugin accepts values with
+type securityLevel int
+
+const (
+	securityNone securityLevel = iota
+	securitySign
+	securityEncrypt
+)
+
+func parseSecurityLevel(s string) (securityLevel, error) {
+	switch s {
+	case "", "none":
+		return securityNone, nil
+	case "sign":
+		return securitySign, nil
+	case "encrypt":
+		return securityEncrypt, nil
+	}
+	return 0, fmt.Errorf("unknown security level %q, must be none, sign or "+
+		"encrypt", s)
+}
+
+// valueList is the values of one type instance of a plugin instance
+type valueList struct {
+	Host           string
+	Plugin         string
+	PluginInstance string
+	Type           string
+	TypeInstance   string
+	Time           time.Time
+	Values         []interface{}
+}
+
+// packetParser decodes packets into value lists
+type packetParser struct {
+	// level is the security values must be sent with
+	level securityLevel
+	// auth maps users to their passwords
+	auth map[string]string
+}
+
+// parse decodes a packet. It returns the value lists decoded until an
+// invalid part, if any, along with the error.
+func (p *packetParser) parse(buf []byte) ([]valueList, error) {
+	var vl valueList
+	return p.parseParts(buf, securityNone, &vl, nil)
+}
+
+// parseParts decodes parts sent with the given security. vl holds the
+// host, plugin, type and time set by earlier parts, which apply to the
+// values of later parts.
+func (p *packetParser) parseParts(
+	buf []byte,
+	security securityLevel,
+	vl *valueList,
+	lists []valueList,
+) ([]valueList, error) {
+	for len(buf) > 0 {
+		if len(buf) < 4 {
+			return lists, errors.New("truncated part header")
+		}
+		typ := binary.BigEndian.Uint16(buf[0:2])
+		length := int(binary.BigEndian.Uint16(buf[2:4]))
+		if length < 4 || length > len(buf) {
+			return lists, fmt.Errorf("invalid length %d of part 0x%04x", length, typ)
+		}
+		body := buf[4:length]
+		buf = buf[length:]
+
+		var err error
+		switch typ {
+		case partHost:
+			vl.Host, err = parseString(body)
+		case partPlugin:
+			vl.Plugin, err = parseString(body)
+		case partPluginInstance:
+			vl.PluginInstance, err = parseString(body)
+		case partType:
+			vl.Type, err = parseString(body)
+		case partTypeInstance:
+			vl.TypeInstance, err = parseString(body)
+		case partTime, partTimeHR:
+			if len(body) != 8 {
+				return lists, errors.New("invalid time part")
+			}
+			t := binary.BigEndian.Uint64(body)
+			if typ == partTime {
+				vl.Time = time.Unix(int64(t), 0)
+			} else {
+				// high resolution times are in 2^-30 seconds
+				vl.Time = time.Unix(int64(t>>30), int64((t&(1<<30-1))*1e9>>30))
+			}
+		case partValues:
+			if security < p.level {
+				return lists, fmt.Errorf("values of %s/%s from %s aren't %s",
+					vl.Plugin, vl.Type, vl.Host, p.levelName())
+			}
+			values, err := parseValues(body)
+			if err != nil {
+				return lists, err
+			}
+			list := *vl
+			list.Values = values
+			lists = append(lists, list)
+		case partSignature:
+			// the signature covers the rest of the packet
+			security, err = p.verify(body, buf, security)
+		case partEncryption:
+			var plain []byte
+			plain, err = p.decrypt(body)
+			if err == nil {
+				lists, err = p.parseParts(plain, securityEncrypt, vl, lists)
+			}
+		default:
+			// intervals, notifications and unknown parts are skipped
+		}
+		if err != nil {
+			return lists, err
+		}
+	}
+	return lists, nil
+}
+
+func (p *packetParser) levelName() string {
+	if p.level == securityEncrypt {
+		return "encrypted"
+	}
+	return "signed"
+}
+
+// verify checks the HMAC-SHA256 signature of the rest of the packet, and
+// returns the security of the rest of the packet. Packets of users missing
+// from the auth file are only accepted, unverified, with no security.
+func (p *packetParser) verify(
+	body []byte,
+	rest []byte,
+	security securityLevel,
+) (securityLevel, error) {
+	if len(body) <= sha256.Size {
+		return security, errors.New("invalid signature part")
+	}
+	user := string(body[sha256.Size:])
+	password, ok := p.auth[user]
+	if !ok {
+		if p.level > securityNone {
+			return security, fmt.Errorf("unknown user %q", user)
+		}
+		return security, nil
+	}
+
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write(body[sha256.Size:])
+	mac.Write(rest)
+	if !hmac.Equal(mac.Sum(nil), body[:sha256.Size]) {
+		return security, fmt.Errorf("invalid signature of user %q", user)
+	}
+	if security < securitySign {
+		security = securitySign
+	}
+	return security, nil
+}
+
+// decrypt decrypts an encryption part, AES-256 in OFB mode keyed with the
+// SHA-256 of the user's password, and checks its SHA-1 checksum
+func (p *packetParser) decrypt(body []byte) ([]byte, error) {
+	if len(body) < 2 {
+		return nil, errors.New("invalid encryption part")
+	}
+	n := int(binary.BigEndian.Uint16(body[0:2]))
+	body = body[2:]
+	if len(body) < n+aes.BlockSize+sha1.Size {
+		return nil, errors.New("invalid encryption part")
+	}
+	user := string(body[:n])
+	iv := body[n : n+aes.BlockSize]
+	data := body[n+aes.BlockSize:]
+
+	password, ok := p.auth[user]
+	if !ok {
+		return nil, fmt.Errorf("unknown user %q", user)
+	}
+	key := sha256.Sum256([]byte(password))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(data))
+	cipher.NewOFB(block, iv).XORKeyStream(plain, data)
+
+	sum := sha1.Sum(plain[sha1.Size:])
+	if !bytes.Equal(sum[:], plain[:sha1.Size]) {
+		return nil, fmt.Errorf("invalid checksum of encrypted data of user %q, "+
+			"wrong password?", user)
+	}
+	return plain[sha1.Size:], nil
+}
+
+// parseString decodes a nul terminated string
+func parseString(body []byte) (string, error) {
+	if len(body) == 0 || body[len(body)-1] != 0 {
+		return "", errors.New("string part isn't nul terminated")
+	}
+	return string(body[:len(body)-1]), nil
+}
+
+// parseValues decodes a values part: the number of values, their data
+// source types, and the values. Gauges are little endian doubles, the
+// other types big endian integers.
+func parseValues(body []byte) ([]interface{}, error) {
+	if len(body) < 2 {
+		return nil, errors.New("invalid values part")
+	}
+	n := int(binary.BigEndian.Uint16(body[0:2]))
+	if len(body) != 2+9*n {
+		return nil, errors.New("invalid values part")
+	}
+	types := body[2 : 2+n]
+	data := body[2+n:]
+
+	values := make([]interface{}, n)
+	for i, ds := range types {
+		v := data[8*i : 8*i+8]
+		switch ds {
+		case dsGauge:
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(v))
+		case dsCounter, dsDerive, dsAbsolute:
+			values[i] = int64(binary.BigEndian.Uint64(v))
+		default:
+			return nil, fmt.Errorf("unknown data source type %d", ds)
+		}
+	}
+	return values, nil
+}
+
+// loadAuthFile reads a collectd auth file, of "user: password" lines
+func loadAuthFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readAuthFile(f)
+}
+
+func readAuthFile(r io.Reader) (map[string]string, error) {
+	auth := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("line %d: expected user: password", n)
+		}
+		auth[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+	}
+	return auth, scanner.Err()
+}
@@ -0,0 +1,194 @@
+package collectd
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// packet builds packets of collectd's binary network protocol
+type packet struct {
+	bytes.Buffer
+}
+
+func (p *packet) header(typ uint16, length int) {
+	binary.Write(p, binary.BigEndian, typ)
+	binary.Write(p, binary.BigEndian, uint16(4+length))
+}
+
+func (p *packet) str(typ uint16, s string) *packet {
+	p.header(typ, len(s)+1)
+	p.WriteString(s)
+	p.WriteByte(0)
+	return p
+}
+
+func (p *packet) time(t time.Time) *packet {
+	p.header(partTimeHR, 8)
+	hr := uint64(t.Unix())<<30 | uint64(t.Nanosecond())<<30/1e9
+	binary.Write(p, binary.BigEndian, hr)
+	return p
+}
+
+func (p *packet) values(types []byte, values ...interface{}) *packet {
+	p.header(partValues, 2+9*len(values))
+	binary.Write(p, binary.BigEndian, uint16(len(values)))
+	p.Write(types)
+	for _, v := range values {
+		switch v := v.(type) {
+		case float64:
+			binary.Write(p, binary.LittleEndian, math.Float64bits(v))
+		case int64:
+			binary.Write(p, binary.BigEndian, v)
+		}
+	}
+	return p
+}
+
+// cpuPacket is a packet of the values of 2 cpu states of a host
+func cpuPacket(t time.Time) *packet {
+	p := &packet{}
+	p.str(partHost, "web1").time(t).str(partPlugin, "cpu").
+		str(partPluginInstance, "0").str(partType, "cpu")
+	p.str(partTypeInstance, "idle").values([]byte{dsDerive}, int64(1000))
+	p.str(partTypeInstance, "user").values([]byte{dsDerive}, int64(20))
+	p.str(partPlugin, "load").str(partPluginInstance, "").
+		str(partType, "load").str(partTypeInstance, "").
+		values([]byte{dsGauge, dsGauge, dsGauge}, 0.5, 0.25, 0.125)
+	return p
+}
+
+// sign signs a packet as collectd does, with a signature part followed by
+// the packet
+func sign(p *packet, user, password string) []byte {
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write([]byte(user))
+	mac.Write(p.Bytes())
+
+	signed := &packet{}
+	signed.header(partSignature, sha256.Size+len(user))
+	signed.Write(mac.Sum(nil))
+	signed.WriteString(user)
+	signed.Write(p.Bytes())
+	return signed.Bytes()
+}
+
+// encrypt encrypts a packet as collectd does, into an encryption part
+func encrypt(p *packet, user, password string) []byte {
+	sum := sha1.Sum(p.Bytes())
+	plain := append(sum[:], p.Bytes()...)
+	iv := bytes.Repeat([]byte{7}, aes.BlockSize)
+	key := sha256.Sum256([]byte(password))
+	block, _ := aes.NewCipher(key[:])
+	data := make([]byte, len(plain))
+	cipher.NewOFB(block, iv).XORKeyStream(data, plain)
+
+	encrypted := &packet{}
+	encrypted.header(partEncryption, 2+len(user)+len(iv)+len(data))
+	binary.Write(encrypted, binary.BigEndian, uint16(len(user)))
+	encrypted.WriteString(user)
+	encrypted.Write(iv)
+	encrypted.Write(data)
+	return encrypted.Bytes()
+}
+
+func TestParsePacket(t *testing.T) {
+	now := time.Unix(1445000000, 500000000)
+	p := &packetParser{}
+	lists, err := p.parse(cpuPacket(now).Bytes())
+	require.NoError(t, err)
+	require.Len(t, lists, 3)
+
+	assert.Equal(t, valueList{
+		Host:           "web1",
+		Plugin:         "cpu",
+		PluginInstance: "0",
+		Type:           "cpu",
+		TypeInstance:   "user",
+		Time:           now,
+		Values:         []interface{}{int64(20)},
+	}, lists[1])
+	assert.Equal(t, "load", lists[2].Plugin)
+	assert.Equal(t, "", lists[2].PluginInstance)
+	assert.Equal(t, []interface{}{0.5, 0.25, 0.125}, lists[2].Values)
+}
+
+func TestParsePacketInvalid(t *testing.T) {
+	p := &packetParser{}
+
+	// values decoded before an invalid part are kept
+	buf := cpuPacket(time.Now()).Bytes()
+	lists, err := p.parse(append(buf, 0, 2, 0, 9, 'x'))
+	assert.Error(t, err)
+	assert.Len(t, lists, 3)
+
+	bad := []*packet{
+		(&packet{}).str(partHost, "web1").values([]byte{9}, int64(1)),
+		(&packet{}).values([]byte{dsGauge, dsGauge}, 1.0),
+	}
+	for _, packet := range bad {
+		_, err := p.parse(packet.Bytes())
+		assert.Error(t, err)
+	}
+	_, err = p.parse([]byte{0, 0, 0, 5, 'a'})
+	assert.Error(t, err)
+	_, err = p.parse([]byte{0, 0, 0})
+	assert.Error(t, err)
+}
+
+func TestParsePacketSecurity(t *testing.T) {
+	auth := map[string]string{"alice": "secret"}
+	packets := map[string][]byte{
+		"plain":           cpuPacket(time.Now()).Bytes(),
+		"signed":          sign(cpuPacket(time.Now()), "alice", "secret"),
+		"encrypted":       encrypt(cpuPacket(time.Now()), "alice", "secret"),
+		"unknown signer":  sign(cpuPacket(time.Now()), "bob", "secret"),
+		"bad signature":   sign(cpuPacket(time.Now()), "alice", "wrong"),
+		"unknown crypter": encrypt(cpuPacket(time.Now()), "bob", "secret"),
+		"bad encryption":  encrypt(cpuPacket(time.Now()), "alice", "wrong"),
+	}
+	accepted := map[securityLevel][]string{
+		securityNone:    {"plain", "signed", "encrypted", "unknown signer"},
+		securitySign:    {"signed", "encrypted"},
+		securityEncrypt: {"encrypted"},
+	}
+
+	for level, names := range accepted {
+		p := &packetParser{level: level, auth: auth}
+		for name, buf := range packets {
+			lists, err := p.parse(buf)
+			want := false
+			for _, n := range names {
+				want = want || n == name
+			}
+			if want {
+				assert.NoError(t, err, "level %d, %s", level, name)
+				assert.Len(t, lists, 3, "level %d, %s", level, name)
+			} else {
+				assert.Error(t, err, "level %d, %s", level, name)
+				assert.Len(t, lists, 0, "level %d, %s", level, name)
+			}
+		}
+	}
+}
+
+func TestReadAuthFile(t *testing.T) {
+	auth, err := readAuthFile(strings.NewReader(
+		"# users\nalice: secret\n\nbob:  p:ss \n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"alice": "secret", "bob": "p:ss"}, auth)
+
+	_, err = readAuthFile(strings.NewReader("alice secret\n"))
+	assert.Error(t, err)
+}
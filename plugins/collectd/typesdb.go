@@ -0,0 +1,78 @@
+package collectd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// typesDB maps collectd types to the names of their data sources, in the
+// order their values are sent
+type typesDB map[string][]string
+
+// loadTypesDB reads types.db files, later files overriding the types of
+// earlier ones
+func loadTypesDB(paths []string) (typesDB, error) {
+	db := make(typesDB)
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		err = db.read(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+	}
+	return db, nil
+}
+
+// read reads types.db lines, such as
+//     load  shortterm:GAUGE:0:5000, midterm:GAUGE:0:5000, longterm:GAUGE:0:5000
+func (db typesDB) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return fmt.Errorf("line %d: type %q has no data sources", n, fields[0])
+		}
+
+		var names []string
+		for _, ds := range strings.Split(strings.Join(fields[1:], " "), ",") {
+			parts := strings.Split(strings.TrimSpace(ds), ":")
+			if len(parts) != 4 || parts[0] == "" {
+				return fmt.Errorf("line %d: invalid data source %q", n,
+					strings.TrimSpace(ds))
+			}
+			names = append(names, parts[0])
+		}
+		db[fields[0]] = names
+	}
+	return scanner.Err()
+}
+
+// names returns the data source names of the n values of type typ. Types
+// missing from types.db get "value" for a single value, and "value0",
+// "value1"... for several.
+func (db typesDB) names(typ string, n int) []string {
+	if names, ok := db[typ]; ok && len(names) == n {
+		return names
+	}
+	if n == 1 {
+		return []string{"value"}
+	}
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("value%d", i)
+	}
+	return names
+}
@@ -0,0 +1,338 @@
+package execd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/gonuts/go-shellquote"
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/internal/parsers"
+	"github.com/influxdb/telegraf/internal/service"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+const sampleConfig = `
+  # the command to run, it is started once and restarted if it exits
+  command = "/usr/bin/mycollector --foo=bar"
+
+  # how the command is told to gather metrics on each interval:
+  #   "stdin":   a newline is written to its stdin
+  #   "SIGUSR1": it is sent a SIGUSR1 signal
+  #   "none":    it writes metrics on its own schedule
+  signal = "stdin"
+  # how long to wait for the command to respond to the signal before adding
+  # the metrics it wrote. Metrics written later are added on the next
+  # interval.
+  response_timeout = "1s"
+
+  # delay before restarting the command after it exits, doubled after every
+  # restart up to 5m
+  restart_delay = "10s"
+
+  # format of the metrics written to stdout, "influx" or "json"
+  data_format = "influx"
+  # measurement name and tag keys of json metrics
+  # metric_name = "mycollector"
+  # tag_keys = ["host"]
+
+  # maximum number of points to buffer between collection intervals
+  point_buffer = 100000
+`
+
+const (
+	maxRestartDelay = 5 * time.Minute
+	// a command that ran for this long is restarted after restart_delay
+	// again, rather than after the backed off delay
+	resetRestartDelay = time.Minute
+	stopTimeout       = 5 * time.Second
+	// the command's response to a signal is complete once it wrote nothing
+	// for this long
+	responseQuiet = 100 * time.Millisecond
+)
+
+type Execd struct {
+	Command         string            `doc:"the command to run, with its arguments"`
+	Signal          string            `doc:"how to ask for points each interval: stdin, SIGUSR1 or none"`
+	ResponseTimeout internal.Duration `doc:"how long to wait for the command to respond to the signal"`
+	RestartDelay    internal.Duration `doc:"delay before restarting the command when it exits"`
+	DataFormat      string            `doc:"format of the command's output: influx or json"`
+	MetricName      string            `doc:"measurement name of json points"`
+	TagKeys         []string          `doc:"json keys to use as tags"`
+	PointBuffer     int               `doc:"maximum number of points to buffer between collection intervals"`
+
+	sync.Mutex
+	args   []string
+	parser parsers.Parser
+	buffer service.Buffer
+	// read is notified of every line read from stdout
+	read     chan struct{}
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	done     chan struct{}
+	stopped  chan struct{}
+	stopping bool
+
+	log *logger.Logger
+}
+
+func NewExecd() *Execd {
+	return &Execd{
+		Signal:          "stdin",
+		ResponseTimeout: internal.Duration{Duration: time.Second},
+		RestartDelay:    internal.Duration{Duration: 10 * time.Second},
+		MetricName:      "execd",
+		PointBuffer:     service.DefaultPointBuffer,
+	}
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Description() string {
+	return "Run a long-running command and read the metrics it writes to stdout"
+}
+
+func (e *Execd) SetLogger(log *logger.Logger) {
+	e.log = log
+}
+
+func (e *Execd) Start() error {
+	args, err := shellquote.Split(e.Command)
+	if err != nil || len(args) == 0 {
+		return fmt.Errorf("execd: unable to parse command, %s", err)
+	}
+	e.args = args
+
+	switch e.Signal {
+	case "stdin", "SIGUSR1", "none":
+	default:
+		return fmt.Errorf("execd: unknown signal %q", e.Signal)
+	}
+
+	e.parser, err = parsers.NewParser(&parsers.Config{
+		DataFormat: e.DataFormat,
+		MetricName: e.MetricName,
+		TagKeys:    e.TagKeys,
+	})
+	if err != nil {
+		return err
+	}
+
+	e.buffer.SetMax(e.PointBuffer)
+	e.read = make(chan struct{}, 1)
+	output, err := e.startCommand()
+	if err != nil {
+		return err
+	}
+
+	e.done = make(chan struct{})
+	e.stopped = make(chan struct{})
+	go e.supervise(output)
+	e.log.Infof("Started command %s", e.Command)
+	return nil
+}
+
+// startCommand starts the command, and returns a WaitGroup that is done
+// once its stdout and stderr are closed.
+func (e *Execd) startCommand() (*sync.WaitGroup, error) {
+	cmd := exec.Command(e.args[0], e.args[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	if e.stopping {
+		return nil, errors.New("execd: stopped")
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("execd: %s for command '%s'", err, e.Command)
+	}
+	e.cmd = cmd
+	e.stdin = stdin
+
+	var output sync.WaitGroup
+	output.Add(2)
+	go func() {
+		defer output.Done()
+		e.readMetrics(stdout)
+	}()
+	go func() {
+		defer output.Done()
+		e.readErrors(stderr)
+	}()
+	return &output, nil
+}
+
+// supervise waits for the command to exit, and restarts it with backoff
+// until the plugin is stopped.
+func (e *Execd) supervise(output *sync.WaitGroup) {
+	defer close(e.stopped)
+
+	delay := e.RestartDelay.Duration
+	for {
+		e.Lock()
+		cmd := e.cmd
+		e.Unlock()
+
+		started := time.Now()
+		output.Wait()
+		err := cmd.Wait()
+
+		select {
+		case <-e.done:
+			return
+		default:
+		}
+
+		if time.Since(started) >= resetRestartDelay {
+			delay = e.RestartDelay.Duration
+		}
+		if err == nil {
+			err = errors.New("exit status 0")
+		}
+
+		for {
+			e.log.Errorf("Command exited (%s), restarting in %s", err, delay)
+			select {
+			case <-e.done:
+				return
+			case <-time.After(delay):
+			}
+
+			delay *= 2
+			if delay > maxRestartDelay {
+				delay = maxRestartDelay
+			}
+
+			output, err = e.startCommand()
+			if err == nil {
+				break
+			}
+		}
+	}
+}
+
+func (e *Execd) readMetrics(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		points, err := e.parser.Parse(scanner.Bytes())
+		if err != nil {
+			e.log.Errorf("Could not parse metrics: %s, error: %s",
+				scanner.Text(), err)
+			continue
+		}
+
+		e.buffer.Add(points...)
+		select {
+		case e.read <- struct{}{}:
+		default:
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		e.log.Errorf("Error reading stdout: %s", err)
+	}
+}
+
+func (e *Execd) readErrors(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		e.log.Errorf("stderr: %s", scanner.Text())
+	}
+}
+
+func (e *Execd) Stop() {
+	close(e.done)
+
+	e.Lock()
+	e.stopping = true
+	e.stdin.Close()
+	if e.cmd.Process != nil {
+		terminate(e.cmd.Process)
+	}
+	e.Unlock()
+
+	select {
+	case <-e.stopped:
+	case <-time.After(stopTimeout):
+		e.Lock()
+		e.cmd.Process.Kill()
+		e.Unlock()
+		<-e.stopped
+	}
+}
+
+// Gather signals the command to gather metrics, waits for its response, and
+// adds the metrics it has written since the last Gather.
+func (e *Execd) Gather(acc plugins.Accumulator) error {
+	err := e.signal()
+	if err == nil && e.Signal != "none" {
+		e.waitForResponse()
+	}
+	e.buffer.Gather(acc, e.log)
+	return err
+}
+
+func (e *Execd) signal() error {
+	e.Lock()
+	defer e.Unlock()
+
+	// only the lines read from now on are the response
+	select {
+	case <-e.read:
+	default:
+	}
+
+	var err error
+	switch e.Signal {
+	case "stdin":
+		_, err = io.WriteString(e.stdin, "\n")
+	case "SIGUSR1":
+		err = signalGather(e.cmd.Process)
+	}
+	if err != nil {
+		return fmt.Errorf("execd: unable to signal command '%s', %s", e.Command, err)
+	}
+	return nil
+}
+
+// waitForResponse waits up to response_timeout for the command to write its
+// response, which is complete once it wrote nothing for responseQuiet.
+func (e *Execd) waitForResponse() {
+	timeout := time.After(e.ResponseTimeout.Duration)
+	quiet := timeout
+	for {
+		select {
+		case <-e.read:
+			quiet = time.After(responseQuiet)
+		case <-quiet:
+			return
+		case <-timeout:
+			return
+		case <-e.done:
+			return
+		}
+	}
+}
+
+func init() {
+	plugins.Add("execd", func() plugins.Plugin {
+		return NewExecd()
+	})
+}
@@ -0,0 +1,16 @@
+// +build !windows
+
+package execd
+
+import (
+	"os"
+	"syscall"
+)
+
+func signalGather(p *os.Process) error {
+	return p.Signal(syscall.SIGUSR1)
+}
+
+func terminate(p *os.Process) error {
+	return p.Signal(syscall.SIGTERM)
+}
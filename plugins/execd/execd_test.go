@@ -0,0 +1,81 @@
+package execd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecdStdinSignal(t *testing.T) {
+	e := NewExecd()
+	e.Command = `sh -c 'while read line; do echo "jobs,queue=a depth=3i"; done'`
+	defer testutil.StartService(t, e)()
+
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, e, &acc, 1)
+
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("jobs",
+		map[string]interface{}{"depth": int64(3)},
+		map[string]string{"queue": "a"}))
+}
+
+func TestExecdWaitsForResponse(t *testing.T) {
+	e := NewExecd()
+	e.Command = `sh -c 'while read line; do sleep 0.05; echo "jobs depth=1i"; echo "jobs depth=2i"; done'`
+	defer testutil.StartService(t, e)()
+
+	// the response to the signal is added by the same Gather
+	var acc testutil.Accumulator
+	require.NoError(t, e.Gather(&acc))
+	assert.Len(t, acc.Points, 2)
+
+	acc = testutil.Accumulator{}
+	require.NoError(t, e.Gather(&acc))
+	assert.Len(t, acc.Points, 2)
+}
+
+func TestExecdJSON(t *testing.T) {
+	e := NewExecd()
+	e.Command = `sh -c 'while read line; do echo "{\"host\": \"a\", \"load\": 0.5}"; done'`
+	e.DataFormat = "json"
+	e.MetricName = "collector"
+	e.TagKeys = []string{"host"}
+	defer testutil.StartService(t, e)()
+
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, e, &acc, 1)
+
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("collector",
+		map[string]interface{}{"load": 0.5},
+		map[string]string{"host": "a"}))
+}
+
+func TestExecdRestart(t *testing.T) {
+	e := NewExecd()
+	e.Command = `sh -c 'echo "starts value=1i"; echo "failing" >&2; exit 1'`
+	e.Signal = "none"
+	e.RestartDelay = internal.Duration{Duration: 10 * time.Millisecond}
+	defer testutil.StartService(t, e)()
+
+	// every start writes a point, so the command was restarted
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, e, &acc, 2)
+}
+
+func TestExecdBadConfig(t *testing.T) {
+	e := NewExecd()
+	assert.Error(t, e.Start())
+
+	e.Command = "sh"
+	e.Signal = "SIGHUP"
+	assert.Error(t, e.Start())
+
+	e.Signal = "stdin"
+	e.DataFormat = "xml"
+	assert.Error(t, e.Start())
+}
@@ -0,0 +1,16 @@
+// +build windows
+
+package execd
+
+import (
+	"errors"
+	"os"
+)
+
+func signalGather(p *os.Process) error {
+	return errors.New("SIGUSR1 is not supported on windows")
+}
+
+func terminate(p *os.Process) error {
+	return p.Kill()
+}
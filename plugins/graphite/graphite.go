@@ -0,0 +1,238 @@
+package graphite
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/internal/parsers"
+	"github.com/influxdb/telegraf/internal/service"
+	"github.com/influxdb/telegraf/plugins"
+	"github.com/influxdb/telegraf/plugins/socket_listener"
+)
+
+const sampleConfig = `
+  # address of the plaintext protocol, "tcp://:2003" or "udp://:2003"
+  service_address = "tcp://:2003"
+  # tcp address of the pickle protocol, ie "tcp://:2004", empty to disable it
+  pickle_address = ""
+
+  # maximum number of concurrent tcp connections of each protocol, 0 for no
+  # limit
+  max_connections = 0
+  # close tcp connections that sent nothing for this long, 0 to never close
+  # them
+  read_timeout = "0s"
+  # maximum number of points of each protocol to buffer between collection
+  # intervals
+  point_buffer = 100000
+
+  # templates mapping the dotted paths to a measurement, tags and a field,
+  # like those of InfluxDB's graphite service, optionally preceded by a
+  # filter and followed by extra tags
+  templates = [
+    "*.app env.service.resource.measurement",
+    "stats.* .host.measurement* region=us-west",
+    "measurement.field*",
+  ]
+  # separator joining the parts of the path that make up the measurement
+  separator = "."
+`
+
+type Graphite struct {
+	ServiceAddress string            `doc:"address of the plaintext protocol: tcp:// or udp://"`
+	PickleAddress  string            `doc:"tcp address of the pickle protocol, empty to disable it"`
+	MaxConnections int               `doc:"maximum number of concurrent tcp connections of each protocol"`
+	ReadTimeout    internal.Duration `doc:"close tcp connections that sent nothing for this long"`
+	PointBuffer    int               `doc:"maximum number of points of each protocol to buffer between collection intervals"`
+	Templates      []string          `doc:"templates mapping paths to a measurement, tags and a field"`
+	Separator      string            `doc:"separator joining the parts of the measurement"`
+
+	parser    parsers.Parser
+	plaintext *socket_listener.SocketListener
+	pickle    service.Listener
+	buffer    service.Buffer
+	wg        sync.WaitGroup
+
+	log *logger.Logger
+}
+
+func NewGraphite() *Graphite {
+	return &Graphite{
+		ServiceAddress: "tcp://:2003",
+		PointBuffer:    service.DefaultPointBuffer,
+		Separator:      ".",
+	}
+}
+
+func (g *Graphite) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *Graphite) Description() string {
+	return "Accept metrics in the Graphite plaintext and pickle protocols"
+}
+
+func (g *Graphite) SetLogger(log *logger.Logger) {
+	g.log = log
+}
+
+func (g *Graphite) Start() error {
+	var err error
+	g.parser, err = parsers.NewParser(&parsers.Config{
+		DataFormat: "graphite",
+		Templates:  g.Templates,
+		Separator:  g.Separator,
+	})
+	if err != nil {
+		return err
+	}
+
+	u, err := url.Parse(g.ServiceAddress)
+	if err != nil {
+		return fmt.Errorf("graphite: invalid address %q: %s",
+			g.ServiceAddress, err)
+	}
+	if u.Scheme != "tcp" && u.Scheme != "udp" {
+		return fmt.Errorf("graphite: unknown scheme %q, must be tcp or udp",
+			u.Scheme)
+	}
+	plaintext := socket_listener.NewSocketListener()
+	plaintext.ServiceAddress = g.ServiceAddress
+	plaintext.MaxConnections = g.MaxConnections
+	plaintext.ReadTimeout = g.ReadTimeout
+	plaintext.PointBuffer = g.PointBuffer
+	plaintext.SetParser(g.parser)
+	plaintext.SetLogger(g.log)
+	if err := plaintext.Start(); err != nil {
+		return err
+	}
+	g.plaintext = plaintext
+
+	if g.PickleAddress == "" {
+		return nil
+	}
+	g.buffer.SetMax(g.PointBuffer)
+	var listener net.Listener
+	u, err = url.Parse(g.PickleAddress)
+	if err == nil && u.Scheme != "tcp" {
+		err = fmt.Errorf("scheme must be tcp")
+	}
+	if err == nil {
+		listener, err = net.Listen("tcp", u.Host)
+	}
+	if err != nil {
+		plaintext.Stop()
+		return fmt.Errorf("graphite: invalid pickle address %q: %s",
+			g.PickleAddress, err)
+	}
+	g.pickle.Listen(listener)
+	g.wg.Add(1)
+	go g.accept(listener)
+	g.log.Infof("Listening for pickles on tcp://%s", listener.Addr())
+	return nil
+}
+
+// Addr returns the address of the plaintext protocol, which has the actual
+// port when the configured port is 0
+func (g *Graphite) Addr() net.Addr {
+	return g.plaintext.Addr()
+}
+
+// PickleAddr returns the address of the pickle protocol, or nil when it is
+// disabled
+func (g *Graphite) PickleAddr() net.Addr {
+	return g.pickle.Addr()
+}
+
+func (g *Graphite) accept(listener net.Listener) {
+	defer g.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !g.pickle.Stopping() {
+				g.log.Errorf("Error accepting connection: %s", err)
+			}
+			return
+		}
+
+		if !g.pickle.Track(conn, g.MaxConnections) {
+			conn.Close()
+			if g.pickle.Stopping() {
+				return
+			}
+			g.log.Warnf("Refused connection from %s, max_connections (%d) "+
+				"reached", conn.RemoteAddr(), g.MaxConnections)
+			continue
+		}
+
+		g.wg.Add(1)
+		go g.readPickles(conn)
+	}
+}
+
+// readPickles reads batches of pickled metrics until the connection is
+// closed. A batch that can't be decoded closes the connection, as the
+// stream can't be resynchronized.
+func (g *Graphite) readPickles(conn net.Conn) {
+	defer g.wg.Done()
+	defer g.pickle.Untrack(conn)
+
+	r := bufio.NewReader(conn)
+	for {
+		if g.ReadTimeout.Duration > 0 {
+			conn.SetReadDeadline(time.Now().Add(g.ReadTimeout.Duration))
+		}
+		lines, err := readPickle(r)
+		if err != nil {
+			if err != io.EOF && !g.pickle.Stopping() {
+				g.log.Errorf("Error reading pickle from %s: %s",
+					conn.RemoteAddr(), err)
+			}
+			return
+		}
+		for _, line := range lines {
+			g.parse(line)
+		}
+	}
+}
+
+// parse parses a line into points, to be added on the next Gather
+func (g *Graphite) parse(line string) {
+	points, err := g.parser.Parse([]byte(line))
+	if err != nil {
+		g.log.Errorf("Could not parse metrics: %s, error: %s", line, err)
+		return
+	}
+
+	g.buffer.Add(points...)
+}
+
+func (g *Graphite) Stop() {
+	g.pickle.Close()
+	g.wg.Wait()
+
+	g.plaintext.Stop()
+}
+
+// Gather adds the metrics received since the last Gather
+func (g *Graphite) Gather(acc plugins.Accumulator) error {
+	if err := g.plaintext.Gather(acc); err != nil {
+		return err
+	}
+
+	g.buffer.Gather(acc, g.log)
+	return nil
+}
+
+func init() {
+	plugins.Add("graphite", func() plugins.Plugin {
+		return NewGraphite()
+	})
+}
@@ -0,0 +1,99 @@
+package graphite
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphitePlaintext(t *testing.T) {
+	for _, scheme := range []string{"tcp", "udp"} {
+		g := NewGraphite()
+		g.ServiceAddress = scheme + "://127.0.0.1:0"
+		testutil.StartService(t, g)
+
+		conn, err := net.Dial(scheme, g.Addr().String())
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("servers.a.cpu 1.5 1445000000\nbad\n"))
+		require.NoError(t, err)
+		conn.Close()
+
+		var acc testutil.Accumulator
+		testutil.GatherUntil(t, g, &acc, 1)
+		g.Stop()
+
+		require.Len(t, acc.Points, 1, scheme)
+		assert.NoError(t, acc.ValidateTaggedFieldsValue("servers.a.cpu",
+			map[string]interface{}{"value": 1.5}, nil), scheme)
+		assert.Equal(t, time.Unix(1445000000, 0).UTC(),
+			acc.Points[0].Time.UTC(), scheme)
+	}
+}
+
+func TestGraphiteTemplates(t *testing.T) {
+	g := NewGraphite()
+	g.ServiceAddress = "tcp://127.0.0.1:0"
+	g.Templates = []string{
+		"servers.* .host.measurement.field region=us-west",
+	}
+	g.Separator = "_"
+	defer testutil.StartService(t, g)()
+
+	conn, err := net.Dial("tcp", g.Addr().String())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("servers.a.cpu.idle 98 1445000000\n" +
+		"app.requests.count 10 1445000000\n"))
+	require.NoError(t, err)
+	conn.Close()
+
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, g, &acc, 2)
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("cpu",
+		map[string]interface{}{"idle": float64(98)},
+		map[string]string{"host": "a", "region": "us-west"}))
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("app_requests_count",
+		map[string]interface{}{"value": float64(10)}, nil))
+}
+
+func TestGraphitePickle(t *testing.T) {
+	g := NewGraphite()
+	g.ServiceAddress = "udp://127.0.0.1:0"
+	g.PickleAddress = "tcp://127.0.0.1:0"
+	defer testutil.StartService(t, g)()
+
+	conn, err := net.Dial("tcp", g.PickleAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write(frame(pickles["protocol 2"]))
+	require.NoError(t, err)
+	_, err = conn.Write(frame(pickles["protocol 0"]))
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, g, &acc, 4)
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("servers.a.cpu",
+		map[string]interface{}{"value": 1.5}, nil))
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("servers.b.cpu",
+		map[string]interface{}{"value": float64(2)}, nil))
+}
+
+func TestGraphiteBadConfig(t *testing.T) {
+	g := NewGraphite()
+	g.ServiceAddress = "unix:///tmp/graphite.sock"
+	assert.Error(t, g.Start())
+
+	g = NewGraphite()
+	g.ServiceAddress = "tcp://127.0.0.1:0"
+	g.Templates = []string{"a.b.c d e f g"}
+	assert.Error(t, g.Start())
+
+	g = NewGraphite()
+	g.ServiceAddress = "tcp://127.0.0.1:0"
+	g.PickleAddress = "udp://127.0.0.1:0"
+	assert.Error(t, g.Start())
+}
@@ -0,0 +1,429 @@
+package graphite
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxPickleSize bounds the size of a pickled batch of metrics
+const maxPickleSize = 1 << 20
+
+// readPickle reads a batch of metrics sent to carbon's pickle port: a four
+// byte big endian length, followed by a pickled list of
+//     (path, (timestamp, value))
+// tuples. It returns the metrics as plaintext lines.
+func readPickle(r io.Reader) ([]string, error) {
+	var length uint32
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	if length > maxPickleSize {
+		return nil, fmt.Errorf("pickle of %d bytes is larger than %d bytes",
+			length, maxPickleSize)
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	v, err := unpickle(buf)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.New("pickle is not a list")
+	}
+
+	lines := make([]string, 0, len(list))
+	for _, item := range list {
+		metric, ok := item.([]interface{})
+		if !ok || len(metric) != 2 {
+			return nil, errors.New("metric is not a (path, datapoint) tuple")
+		}
+		path, ok := metric[0].(string)
+		if !ok {
+			return nil, errors.New("metric path is not a string")
+		}
+		datapoint, ok := metric[1].([]interface{})
+		if !ok || len(datapoint) != 2 {
+			return nil, fmt.Errorf("datapoint of %s is not a (timestamp, value) "+
+				"tuple", path)
+		}
+		if datapoint[1] == nil {
+			// carbon sends None for missing values
+			continue
+		}
+		timestamp, err := formatNumber(datapoint[0])
+		if err != nil {
+			return nil, fmt.Errorf("timestamp of %s: %s", path, err)
+		}
+		value, err := formatNumber(datapoint[1])
+		if err != nil {
+			return nil, fmt.Errorf("value of %s: %s", path, err)
+		}
+		lines = append(lines, path+" "+value+" "+timestamp)
+	}
+	return lines, nil
+}
+
+func formatNumber(v interface{}) (string, error) {
+	switch n := v.(type) {
+	case int64:
+		return strconv.FormatInt(n, 10), nil
+	case *big.Int:
+		return n.String(), nil
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	case string:
+		if _, err := strconv.ParseFloat(n, 64); err != nil {
+			return "", fmt.Errorf("%q is not a number", n)
+		}
+		return n, nil
+	}
+	return "", fmt.Errorf("%v is not a number", v)
+}
+
+// mark is pushed on the stack by the MARK opcode
+type mark struct{}
+
+// unpickle decodes the subset of the pickle protocols (0 to 4) that
+// carbon clients use: lists, tuples, strings, numbers, None and booleans.
+// Tuples are decoded as []interface{}, like lists.
+func unpickle(buf []byte) (interface{}, error) {
+	r := bufio.NewReader(bytes.NewReader(buf))
+	var stack []interface{}
+	memo := make(map[int64]interface{})
+
+	pop := func() (interface{}, error) {
+		if len(stack) == 0 {
+			return nil, errors.New("pickle stack underflow")
+		}
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v, nil
+	}
+	// popMark pops the items pushed since the last MARK
+	popMark := func() ([]interface{}, error) {
+		for i := len(stack) - 1; i >= 0; i-- {
+			if _, ok := stack[i].(mark); ok {
+				items := append([]interface{}{}, stack[i+1:]...)
+				stack = stack[:i]
+				return items, nil
+			}
+		}
+		return nil, errors.New("pickle mark not found")
+	}
+	top := func() (interface{}, error) {
+		if len(stack) == 0 {
+			return nil, errors.New("pickle stack underflow")
+		}
+		return stack[len(stack)-1], nil
+	}
+	readN := func(n int) ([]byte, error) {
+		if n < 0 || n > len(buf) {
+			return nil, errors.New("invalid pickle length")
+		}
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	}
+	readUint := func(n int) (int64, error) {
+		b, err := readN(n)
+		if err != nil {
+			return 0, err
+		}
+		var v int64
+		for i := n - 1; i >= 0; i-- {
+			v = v<<8 | int64(b[i])
+		}
+		return v, nil
+	}
+	readLine := func() (string, error) {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		return line[:len(line)-1], nil
+	}
+
+	for {
+		op, err := r.ReadByte()
+		if err != nil {
+			return nil, errors.New("pickle ended without STOP")
+		}
+
+		switch op {
+		case '.': // STOP
+			return pop()
+		case 0x80: // PROTO
+			if _, err := r.ReadByte(); err != nil {
+				return nil, err
+			}
+		case 0x95: // FRAME
+			if _, err := readN(8); err != nil {
+				return nil, err
+			}
+		case '(': // MARK
+			stack = append(stack, mark{})
+		case ']': // EMPTY_LIST
+			stack = append(stack, []interface{}{})
+		case ')': // EMPTY_TUPLE
+			stack = append(stack, []interface{}{})
+		case 'l', 't': // LIST, TUPLE
+			items, err := popMark()
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, items)
+		case 0x85, 0x86, 0x87: // TUPLE1, TUPLE2, TUPLE3
+			n := int(op - 0x84)
+			if len(stack) < n {
+				return nil, errors.New("pickle stack underflow")
+			}
+			items := append([]interface{}{}, stack[len(stack)-n:]...)
+			stack = append(stack[:len(stack)-n], items)
+		case 'a': // APPEND
+			v, err := pop()
+			if err != nil {
+				return nil, err
+			}
+			if err := appendTop(stack, v); err != nil {
+				return nil, err
+			}
+		case 'e': // APPENDS
+			items, err := popMark()
+			if err != nil {
+				return nil, err
+			}
+			if err := appendTop(stack, items...); err != nil {
+				return nil, err
+			}
+		case 'N': // NONE
+			stack = append(stack, nil)
+		case 0x88: // NEWTRUE
+			stack = append(stack, true)
+		case 0x89: // NEWFALSE
+			stack = append(stack, false)
+		case 'K': // BININT1
+			v, err := readUint(1)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, v)
+		case 'M': // BININT2
+			v, err := readUint(2)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, v)
+		case 'J': // BININT
+			v, err := readUint(4)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, int64(int32(v)))
+		case 0x8a: // LONG1
+			n, err := readUint(1)
+			if err != nil {
+				return nil, err
+			}
+			b, err := readN(int(n))
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, decodeLong(b))
+		case 'I': // INT
+			line, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			switch line {
+			case "00":
+				stack = append(stack, false)
+			case "01":
+				stack = append(stack, true)
+			default:
+				v, err := strconv.ParseInt(line, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid pickle INT %q", line)
+				}
+				stack = append(stack, v)
+			}
+		case 'L': // LONG
+			line, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			v, ok := new(big.Int).SetString(strings.TrimSuffix(line, "L"), 10)
+			if !ok {
+				return nil, fmt.Errorf("invalid pickle LONG %q", line)
+			}
+			stack = append(stack, normalizeInt(v))
+		case 'G': // BINFLOAT
+			b, err := readN(8)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, math.Float64frombits(binary.BigEndian.Uint64(b)))
+		case 'F': // FLOAT
+			line, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			v, err := strconv.ParseFloat(line, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pickle FLOAT %q", line)
+			}
+			stack = append(stack, v)
+		case 'S': // STRING
+			line, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			s, err := strconv.Unquote(line)
+			if err != nil {
+				// python quotes with single quotes
+				if len(line) >= 2 && line[0] == '\'' && line[len(line)-1] == '\'' {
+					s = line[1 : len(line)-1]
+				} else {
+					return nil, fmt.Errorf("invalid pickle STRING %q", line)
+				}
+			}
+			stack = append(stack, s)
+		case 'V': // UNICODE
+			line, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, line)
+		case 'U', 0x8c, 'C': // SHORT_BINSTRING, SHORT_BINUNICODE, SHORT_BINBYTES
+			n, err := readUint(1)
+			if err != nil {
+				return nil, err
+			}
+			b, err := readN(int(n))
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, string(b))
+		case 'T', 'X', 'B': // BINSTRING, BINUNICODE, BINBYTES
+			n, err := readUint(4)
+			if err != nil {
+				return nil, err
+			}
+			b, err := readN(int(n))
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, string(b))
+		case 'p': // PUT
+			line, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			id, err := strconv.ParseInt(line, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pickle PUT %q", line)
+			}
+			v, err := top()
+			if err != nil {
+				return nil, err
+			}
+			memo[id] = v
+		case 'q', 'r': // BINPUT, LONG_BINPUT
+			n := 1
+			if op == 'r' {
+				n = 4
+			}
+			id, err := readUint(n)
+			if err != nil {
+				return nil, err
+			}
+			v, err := top()
+			if err != nil {
+				return nil, err
+			}
+			memo[id] = v
+		case 0x94: // MEMOIZE
+			v, err := top()
+			if err != nil {
+				return nil, err
+			}
+			memo[int64(len(memo))] = v
+		case 'g': // GET
+			line, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			id, err := strconv.ParseInt(line, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pickle GET %q", line)
+			}
+			v, ok := memo[id]
+			if !ok {
+				return nil, fmt.Errorf("pickle memo %d not found", id)
+			}
+			stack = append(stack, v)
+		case 'h', 'j': // BINGET, LONG_BINGET
+			n := 1
+			if op == 'j' {
+				n = 4
+			}
+			id, err := readUint(n)
+			if err != nil {
+				return nil, err
+			}
+			v, ok := memo[id]
+			if !ok {
+				return nil, fmt.Errorf("pickle memo %d not found", id)
+			}
+			stack = append(stack, v)
+		default:
+			return nil, fmt.Errorf("unsupported pickle opcode 0x%02x", op)
+		}
+	}
+}
+
+// appendTop appends items to the list at the top of the stack. Lists are
+// slices, so a memoized list doesn't see later appends, which carbon
+// clients never rely on.
+func appendTop(stack []interface{}, items ...interface{}) error {
+	if len(stack) == 0 {
+		return errors.New("pickle stack underflow")
+	}
+	list, ok := stack[len(stack)-1].([]interface{})
+	if !ok {
+		return errors.New("pickle APPEND to something that isn't a list")
+	}
+	stack[len(stack)-1] = append(list, items...)
+	return nil
+}
+
+// decodeLong decodes a little endian two's complement integer
+func decodeLong(b []byte) interface{} {
+	v := new(big.Int)
+	for i := len(b) - 1; i >= 0; i-- {
+		v.Lsh(v, 8)
+		v.Or(v, big.NewInt(int64(b[i])))
+	}
+	if len(b) > 0 && b[len(b)-1]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	return normalizeInt(v)
+}
+
+func normalizeInt(v *big.Int) interface{} {
+	if v.BitLen() < 64 {
+		return v.Int64()
+	}
+	return v
+}
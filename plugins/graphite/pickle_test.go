@@ -0,0 +1,74 @@
+package graphite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the pickles of
+//     [("servers.a.cpu", (1445000000, 1.5)),
+//      ("servers.b.cpu", (1445000000, 2)),
+//      ("servers.c.cpu", (1445000000, None))]
+// in protocols 0, 2 and 4
+var pickles = map[string]string{
+	"protocol 0": "(lp0\n(Vservers.a.cpu\np1\n(I1445000000\nF1.5\ntp2\ntp3\na" +
+		"(Vservers.b.cpu\np4\n(I1445000000\nI2\ntp5\ntp6\na" +
+		"(Vservers.c.cpu\np7\n(I1445000000\nNtp8\ntp9\na.",
+	"protocol 2": "\x80\x02]q\x00(X\r\x00\x00\x00servers.a.cpuq\x01J@\xf3 V" +
+		"G?\xf8\x00\x00\x00\x00\x00\x00\x86q\x02\x86q\x03" +
+		"X\r\x00\x00\x00servers.b.cpuq\x04J@\xf3 VK\x02\x86q\x05\x86q\x06" +
+		"X\r\x00\x00\x00servers.c.cpuq\x07J@\xf3 VN\x86q\x08\x86q\te.",
+	"protocol 4": "\x80\x04\x95\\\x00\x00\x00\x00\x00\x00\x00]\x94(" +
+		"\x8c\rservers.a.cpu\x94J@\xf3 VG?\xf8\x00\x00\x00\x00\x00\x00\x86\x94\x86\x94" +
+		"\x8c\rservers.b.cpu\x94J@\xf3 VK\x02\x86\x94\x86\x94" +
+		"\x8c\rservers.c.cpu\x94J@\xf3 VN\x86\x94\x86\x94e.",
+}
+
+// frame prefixes a pickle with its length, as carbon clients send it
+func frame(pickle string) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint32(len(pickle)))
+	buf.WriteString(pickle)
+	return buf.Bytes()
+}
+
+func TestReadPickle(t *testing.T) {
+	for name, pickle := range pickles {
+		lines, err := readPickle(bytes.NewReader(frame(pickle)))
+		require.NoError(t, err, name)
+		assert.Equal(t, []string{
+			"servers.a.cpu 1.5 1445000000",
+			"servers.b.cpu 2 1445000000",
+		}, lines, name)
+	}
+}
+
+func TestReadPickleErrors(t *testing.T) {
+	bad := []string{
+		// not a list
+		"\x80\x02K\x01.",
+		// a list of numbers
+		"\x80\x02]q\x00K\x01a.",
+		// an unsupported opcode, a dict
+		"\x80\x02}q\x00.",
+		// no STOP
+		"\x80\x02]q\x00",
+		// a datapoint without a value
+		"\x80\x02]q\x00X\x01\x00\x00\x00aJ@\xf3 V\x85\x86a.",
+		// a value that isn't a number
+		"\x80\x02]q\x00X\x01\x00\x00\x00aJ@\xf3 VX\x01\x00\x00\x00b\x86\x86a.",
+	}
+	for _, pickle := range bad {
+		_, err := readPickle(bytes.NewReader(frame(pickle)))
+		assert.Error(t, err, "%q", pickle)
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint32(maxPickleSize+1))
+	_, err := readPickle(&buf)
+	assert.Error(t, err)
+}
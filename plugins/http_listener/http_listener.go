@@ -0,0 +1,230 @@
+package http_listener
+
+import (
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb/models"
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/internal/service"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+const sampleConfig = `
+  # address to serve the InfluxDB HTTP API on
+  service_address = ":8186"
+
+  # timeouts of reading a request and writing its response
+  read_timeout = "10s"
+  write_timeout = "10s"
+
+  # maximum size of a request body, after gzip decompression. Larger
+  # requests are refused with 413 Request Entity Too Large.
+  max_body_size = "32MB"
+
+  # if set, the db query parameter of writes is added as a tag of this name
+  # database_tag = "database"
+
+  # maximum number of points to buffer between collection intervals
+  point_buffer = 100000
+
+  # certificate and key to serve https
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  # only accept clients with a certificate signed by one of these CAs
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+`
+
+// version is reported in the X-Influxdb-Version header, clients check it
+// to know which API they talk to
+const version = "0.9.6"
+
+type HTTPListener struct {
+	ServiceAddress    string            `doc:"address to serve the InfluxDB HTTP API on"`
+	ReadTimeout       internal.Duration `doc:"timeout of reading a request"`
+	WriteTimeout      internal.Duration `doc:"timeout of writing a response"`
+	MaxBodySize       internal.Size     `doc:"maximum size of a request body, after decompression"`
+	DatabaseTag       string            `doc:"tag the db of writes is added as, if set"`
+	PointBuffer       int               `doc:"maximum number of points to buffer between collection intervals"`
+	TLSCert           string            `toml:"tls_cert" doc:"certificate to serve https"`
+	TLSKey            string            `toml:"tls_key" doc:"key to serve https"`
+	TLSAllowedCACerts []string          `toml:"tls_allowed_cacerts" doc:"CAs client certificates must be signed by"`
+
+	service.Listener
+	buffer service.Buffer
+	wg     sync.WaitGroup
+
+	log *logger.Logger
+}
+
+func NewHTTPListener() *HTTPListener {
+	return &HTTPListener{
+		ServiceAddress: ":8186",
+		ReadTimeout:    internal.Duration{Duration: 10 * time.Second},
+		WriteTimeout:   internal.Duration{Duration: 10 * time.Second},
+		MaxBodySize:    internal.Size{Size: 32 * 1024 * 1024},
+		PointBuffer:    service.DefaultPointBuffer,
+	}
+}
+
+func (h *HTTPListener) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *HTTPListener) Description() string {
+	return "Accept metrics written to an InfluxDB compatible HTTP API"
+}
+
+func (h *HTTPListener) SetLogger(log *logger.Logger) {
+	h.log = log
+}
+
+func (h *HTTPListener) Start() error {
+	h.buffer.SetMax(h.PointBuffer)
+	tlsConfig, err := internal.GetServerTLSConfig(
+		h.TLSCert, h.TLSKey, h.TLSAllowedCACerts)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", h.ServiceAddress)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	h.Listen(listener)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/write", h.serveWrite)
+	mux.HandleFunc("/query", h.serveQuery)
+	mux.HandleFunc("/ping", h.servePing)
+	server := &http.Server{
+		Handler:      mux,
+		ReadTimeout:  h.ReadTimeout.Duration,
+		WriteTimeout: h.WriteTimeout.Duration,
+	}
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		server.Serve(listener)
+	}()
+	h.log.Infof("Listening on %s", listener.Addr())
+	return nil
+}
+
+func (h *HTTPListener) serveWrite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		httpError(w, http.StatusMethodNotAllowed, "write needs a POST")
+		return
+	}
+
+	precision := r.URL.Query().Get("precision")
+	switch precision {
+	case "", "n", "ns":
+		precision = "n"
+	case "u", "us", "µ":
+		precision = "u"
+	case "ms", "s", "m", "h":
+	default:
+		httpError(w, http.StatusBadRequest,
+			fmt.Sprintf("invalid precision %q", precision))
+		return
+	}
+
+	body := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, "invalid gzip body: "+err.Error())
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	// read one byte more than allowed, to tell a body of the maximum size
+	// from a larger one
+	buf, err := ioutil.ReadAll(io.LimitReader(body, h.MaxBodySize.Size+1))
+	if err != nil {
+		httpError(w, http.StatusBadRequest, "unable to read body: "+err.Error())
+		return
+	}
+	if int64(len(buf)) > h.MaxBodySize.Size {
+		httpError(w, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("body is larger than %d bytes", h.MaxBodySize.Size))
+		return
+	}
+
+	points, err := models.ParsePointsWithPrecision(buf, time.Now().UTC(), precision)
+	if db := r.URL.Query().Get("db"); db != "" && h.DatabaseTag != "" {
+		for _, pt := range points {
+			pt.AddTag(h.DatabaseTag, db)
+		}
+	}
+
+	if !h.buffer.TryAdd(points...) {
+		// writers retry, like they do when InfluxDB is overloaded
+		httpError(w, http.StatusServiceUnavailable,
+			"the buffer is full, increase point_buffer or retry later")
+		return
+	}
+	if err != nil {
+		// like InfluxDB, the points that could be parsed are kept
+		httpError(w, http.StatusBadRequest, "partial write: "+err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveQuery answers queries with an empty result, so that clients that
+// create their database or check the connection with a query keep working
+func (h *HTTPListener) serveQuery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Influxdb-Version", version)
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, `{"results":[{}]}`)
+}
+
+func (h *HTTPListener) servePing(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Influxdb-Version", version)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// httpError writes an error response in the format of the InfluxDB API
+func httpError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Influxdb-Version", version)
+	w.WriteHeader(code)
+	buf, _ := json.Marshal(map[string]string{"error": msg})
+	w.Write(buf)
+}
+
+func (h *HTTPListener) Stop() {
+	h.Close()
+	h.wg.Wait()
+}
+
+// Gather adds the metrics written since the last Gather
+func (h *HTTPListener) Gather(acc plugins.Accumulator) error {
+	h.buffer.Gather(acc, h.log)
+	return nil
+}
+
+func init() {
+	plugins.Add("http_listener", func() plugins.Plugin {
+		return NewHTTPListener()
+	})
+}
@@ -0,0 +1,148 @@
+package http_listener
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newListener returns a listener on a random port
+func newListener() *HTTPListener {
+	h := NewHTTPListener()
+	h.ServiceAddress = "127.0.0.1:0"
+	return h
+}
+
+// baseURL returns the URL of a started listener
+func baseURL(h *HTTPListener) string {
+	return "http://" + h.Addr().String()
+}
+
+func post(t *testing.T, url string, body string) (*http.Response, string) {
+	resp, err := http.Post(url, "text/plain", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	buf, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(buf)
+}
+
+func TestHTTPListenerWrite(t *testing.T) {
+	h := newListener()
+	h.DatabaseTag = "database"
+	defer testutil.StartService(t, h)()
+	url := baseURL(h)
+
+	resp, _ := post(t, url+"/write?db=mydb&precision=s",
+		"cpu,host=a usage=1.5 1449000000\nmem free=10i 1449000001\n")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	require.Len(t, acc.Points, 2)
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("cpu",
+		map[string]interface{}{"usage": 1.5},
+		map[string]string{"host": "a", "database": "mydb"}))
+	assert.Equal(t, time.Unix(1449000000, 0).UTC(), acc.Points[0].Time)
+	assert.Equal(t, time.Unix(1449000001, 0).UTC(), acc.Points[1].Time)
+}
+
+func TestHTTPListenerGzip(t *testing.T) {
+	h := newListener()
+	defer testutil.StartService(t, h)()
+	url := baseURL(h)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("cpu usage=1\n"))
+	gz.Close()
+
+	req, err := http.NewRequest("POST", url+"/write", &buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	assert.Len(t, acc.Points, 1)
+}
+
+func TestHTTPListenerErrors(t *testing.T) {
+	h := newListener()
+	h.MaxBodySize.Size = 32
+	defer testutil.StartService(t, h)()
+	url := baseURL(h)
+
+	// the valid points of a partial write are kept
+	resp, body := post(t, url+"/write", "cpu usage=1\nbad line\n")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, body, `"error":"partial write`)
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	assert.Len(t, acc.Points, 1)
+
+	resp, _ = post(t, url+"/write?precision=d", "cpu usage=1\n")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = post(t, url+"/write", strings.Repeat("cpu usage=1\n", 3))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	resp, err := http.Get(url + "/write")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	acc = testutil.Accumulator{}
+	require.NoError(t, h.Gather(&acc))
+	assert.Empty(t, acc.Points)
+}
+
+func TestHTTPListenerBufferFull(t *testing.T) {
+	h := newListener()
+	h.PointBuffer = 2
+	defer testutil.StartService(t, h)()
+	url := baseURL(h)
+
+	resp, _ := post(t, url+"/write", "cpu usage=1\n")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	// none of the points are kept when they don't all fit
+	resp, body := post(t, url+"/write", "cpu usage=2\ncpu usage=3\n")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Contains(t, body, "point_buffer")
+
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	assert.Len(t, acc.Points, 1)
+
+	// gathering makes room for the retry
+	resp, _ = post(t, url+"/write", "cpu usage=2\ncpu usage=3\n")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestHTTPListenerPingAndQuery(t *testing.T) {
+	h := newListener()
+	defer testutil.StartService(t, h)()
+	url := baseURL(h)
+
+	resp, err := http.Get(url + "/ping")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("X-Influxdb-Version"))
+
+	resp, body := post(t, url+"/query?q=CREATE+DATABASE+mydb", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"results":[{}]}`, body)
+}
@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/plugins"
 )
 
@@ -43,6 +44,12 @@ type Jolokia struct {
 	Servers []Server
 	Metrics []Metric
 	Tags    map[string]string
+
+	log *logger.Logger
+}
+
+func (j *Jolokia) SetLogger(log *logger.Logger) {
+	j.log = log
 }
 
 func (j *Jolokia) SampleConfig() string {
@@ -51,7 +58,7 @@ func (j *Jolokia) SampleConfig() string {
   context = "/jolokia/read"
 
   # Tags added to each measurements
-  [jolokia.tags]
+  [plugins.jolokia.tags]
     group = "as"
 
   # List of servers exposing jolokia read service
@@ -208,7 +215,8 @@ func (j *Jolokia) Gather(acc plugins.Accumulator) error {
 					acc.Add(measurement, values.(interface{}), tags)
 				}
 			} else {
-				fmt.Printf("Missing key 'value' in '%s' output response\n", requestUrl.String())
+				j.log.Errorf("Missing key 'value' in '%s' output response",
+					requestUrl.String())
 			}
 		}
 	}
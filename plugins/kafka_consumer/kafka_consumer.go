@@ -1,11 +1,11 @@
 package kafka_consumer
 
 import (
-	"log"
 	"strings"
 	"sync"
 
 	"github.com/influxdb/influxdb/models"
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/plugins"
 
 	"github.com/Shopify/sarama"
@@ -33,6 +33,8 @@ type Kafka struct {
 	// doNotCommitMsgs tells the parser not to call CommitUpTo on the consumer
 	// this is mostly for test purposes, but there may be a use-case for it later.
 	doNotCommitMsgs bool
+
+	log *logger.Logger
 }
 
 var sampleConfig = `
@@ -56,6 +58,10 @@ func (k *Kafka) Description() string {
 	return "Read line-protocol metrics from Kafka topic(s)"
 }
 
+func (k *Kafka) SetLogger(log *logger.Logger) {
+	k.log = log
+}
+
 func (k *Kafka) Start() error {
 	k.Lock()
 	defer k.Unlock()
@@ -68,7 +74,7 @@ func (k *Kafka) Start() error {
 	case "newest":
 		config.Offsets.Initial = sarama.OffsetNewest
 	default:
-		log.Printf("WARNING: Kafka consumer invalid offset '%s', using 'oldest'\n",
+		k.log.Warnf("Kafka consumer invalid offset '%s', using 'oldest'",
 			k.Offset)
 		config.Offsets.Initial = sarama.OffsetOldest
 	}
@@ -97,7 +103,7 @@ func (k *Kafka) Start() error {
 
 	// Start the kafka message reader
 	go k.parser()
-	log.Printf("Started the kafka consumer service, peers: %v, topics: %v\n",
+	k.log.Infof("Started the kafka consumer service, peers: %v, topics: %v",
 		k.ZookeeperPeers, k.Topics)
 	return nil
 }
@@ -110,11 +116,11 @@ func (k *Kafka) parser() {
 		case <-k.done:
 			return
 		case err := <-k.errs:
-			log.Printf("Kafka Consumer Error: %s\n", err.Error())
+			k.log.Errorf("Kafka Consumer Error: %s", err.Error())
 		case msg := <-k.in:
 			points, err := models.ParsePoints(msg.Value)
 			if err != nil {
-				log.Printf("Could not parse kafka message: %s, error: %s",
+				k.log.Errorf("Could not parse kafka message: %s, error: %s",
 					string(msg.Value), err.Error())
 			}
 
@@ -123,7 +129,7 @@ func (k *Kafka) parser() {
 				case k.pointChan <- point:
 					continue
 				default:
-					log.Printf("Kafka Consumer buffer is full, dropping a point." +
+					k.log.Warnf("Kafka Consumer buffer is full, dropping a point." +
 						" You may want to increase the point_buffer setting")
 				}
 			}
@@ -144,7 +150,7 @@ func (k *Kafka) Stop() {
 	defer k.Unlock()
 	close(k.done)
 	if err := k.Consumer.Close(); err != nil {
-		log.Printf("Error closing kafka consumer: %s\n", err.Error())
+		k.log.Errorf("Error closing kafka consumer: %s", err.Error())
 	}
 }
 
@@ -0,0 +1,111 @@
+package logparser
+
+import (
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/internal/parsers"
+	"github.com/influxdb/telegraf/internal/service"
+	"github.com/influxdb/telegraf/plugins"
+	"github.com/influxdb/telegraf/plugins/tail"
+)
+
+const sampleConfig = `
+  # log files to parse, as glob patterns, followed like the tail plugin does
+  files = ["/var/log/nginx/access.log"]
+  # read files that exist at startup from the beginning
+  from_beginning = false
+  # file the read offsets are kept in across restarts
+  # state_file = "/var/lib/telegraf/logparser.state"
+
+  # grok patterns that lines are matched against, in order. Captures are
+  # written %{PATTERN:name:modifier}, where the modifier is one of string,
+  # int, float, duration, tag, drop or ts-<layout>.
+  patterns = ["%{COMBINED_LOG_FORMAT}"]
+  # name of the measurement
+  measurement = "nginx_access_log"
+
+  # additional pattern definitions, one per line as NAME followed by its
+  # regular expression, use a ''' string for several lines
+  # custom_patterns = "QUEUE [a-z]+-queue"
+  # files of pattern definitions, in the same format
+  # custom_pattern_files = ["/etc/telegraf/patterns/myapp"]
+`
+
+type LogParser struct {
+	Files              []string          `doc:"glob patterns of the log files to parse"`
+	FromBeginning      bool              `doc:"read files that exist at startup from the beginning"`
+	StateFile          string            `doc:"file the read offsets are kept in across restarts"`
+	PollInterval       internal.Duration `doc:"how often files are checked for new lines"`
+	PointBuffer        int               `doc:"maximum number of points to buffer between collection intervals"`
+	Patterns           []string          `doc:"grok patterns lines are matched against, in order"`
+	Measurement        string            `doc:"name of the measurement"`
+	CustomPatterns     string            `doc:"additional pattern definitions, one per line"`
+	CustomPatternFiles []string          `doc:"files of additional pattern definitions"`
+
+	tail *tail.Tail
+	log  *logger.Logger
+}
+
+func NewLogParser() *LogParser {
+	return &LogParser{
+		PollInterval: internal.Duration{Duration: time.Second},
+		Measurement:  "logparser",
+		PointBuffer:  service.DefaultPointBuffer,
+	}
+}
+
+func (l *LogParser) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *LogParser) Description() string {
+	return "Parse log files, such as access logs, into metrics with grok patterns"
+}
+
+func (l *LogParser) SetLogger(log *logger.Logger) {
+	l.log = log
+}
+
+func (l *LogParser) Start() error {
+	parser, err := parsers.NewParser(&parsers.Config{
+		DataFormat:         "grok",
+		MetricName:         l.Measurement,
+		Patterns:           l.Patterns,
+		CustomPatterns:     l.CustomPatterns,
+		CustomPatternFiles: l.CustomPatternFiles,
+	})
+	if err != nil {
+		return err
+	}
+
+	t := tail.NewTail()
+	t.Files = l.Files
+	t.FromBeginning = l.FromBeginning
+	t.StateFile = l.StateFile
+	t.PollInterval = l.PollInterval
+	t.PointBuffer = l.PointBuffer
+	t.SetParser(parser)
+	t.SetLogger(l.log)
+	if err := t.Start(); err != nil {
+		return err
+	}
+	l.tail = t
+	return nil
+}
+
+func (l *LogParser) Stop() {
+	l.tail.Stop()
+}
+
+// Gather adds the metrics parsed from the lines read since the last Gather
+func (l *LogParser) Gather(acc plugins.Accumulator) error {
+	return l.tail.Gather(acc)
+}
+
+func init() {
+	plugins.Add("logparser", func() plugins.Plugin {
+		return NewLogParser()
+	})
+}
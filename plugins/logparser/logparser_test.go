@@ -0,0 +1,54 @@
+package logparser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdb/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogParserAccessLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logparser")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "access.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte(
+		`10.0.0.1 - - [01/Dec/2015:10:00:00 +0000] "GET /index.html HTTP/1.1" 200 512 "-" "curl/7.43.0"`+"\n"+
+			"not an access log line\n"), 0644))
+
+	l := NewLogParser()
+	l.Files = []string{filepath.Join(dir, "*.log")}
+	l.FromBeginning = true
+	l.Patterns = []string{"%{COMBINED_LOG_FORMAT}"}
+	l.Measurement = "nginx_access_log"
+	require.NoError(t, l.Start())
+	defer l.Stop()
+
+	var acc testutil.Accumulator
+	require.NoError(t, l.Gather(&acc))
+	require.Len(t, acc.Points, 1)
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("nginx_access_log",
+		map[string]interface{}{
+			"client_ip":    "10.0.0.1",
+			"ident":        "-",
+			"auth":         "-",
+			"request":      "/index.html",
+			"http_version": 1.1,
+			"resp_bytes":   int64(512),
+			"referrer":     "-",
+			"agent":        "curl/7.43.0",
+		},
+		map[string]string{"verb": "GET", "resp_code": "200"}))
+}
+
+func TestLogParserBadPattern(t *testing.T) {
+	l := NewLogParser()
+	l.Files = []string{"/var/log/*.log"}
+	l.Patterns = []string{"%{NOT_A_PATTERN}"}
+	assert.Error(t, l.Start())
+}
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/plugins"
 	"gopkg.in/mgo.v2"
 )
@@ -17,6 +18,8 @@ type MongoDB struct {
 	Servers []string
 	Ssl     Ssl
 	mongos  map[string]*Server
+
+	log *logger.Logger
 }
 
 type Ssl struct {
@@ -45,6 +48,10 @@ var localhost = &url.URL{Host: "127.0.0.1:27017"}
 
 // Reads stats from all configured servers accumulates stats.
 // Returns one of the errors encountered while gather stats (if any).
+func (m *MongoDB) SetLogger(log *logger.Logger) {
+	m.log = log
+}
+
 func (m *MongoDB) Gather(acc plugins.Accumulator) error {
 	if len(m.Servers) == 0 {
 		m.gatherServer(m.getMongoServer(localhost), acc)
@@ -120,7 +127,7 @@ func (m *MongoDB) gatherServer(server *Server, acc plugins.Accumulator) error {
 			dialInfo.DialServer = func(addr *mgo.ServerAddr) (net.Conn, error) {
 				conn, err := tls.Dial("tcp", addr.String(), tlsConfig)
 				if err != nil {
-					fmt.Printf("error in Dial, %s\n", err.Error())
+					m.log.Errorf("error in Dial, %s", err.Error())
 				}
 				return conn, err
 			}
@@ -128,7 +135,7 @@ func (m *MongoDB) gatherServer(server *Server, acc plugins.Accumulator) error {
 
 		sess, err := mgo.DialWithInfo(dialInfo)
 		if err != nil {
-			fmt.Printf("error dialing over ssl, %s\n", err.Error())
+			m.log.Errorf("error dialing over ssl, %s", err.Error())
 			return fmt.Errorf("Unable to connect to MongoDB, %s\n", err.Error())
 		}
 		server.Session = sess
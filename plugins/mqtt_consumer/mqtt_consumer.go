@@ -0,0 +1,269 @@
+package mqtt_consumer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	paho "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/internal/parsers"
+	"github.com/influxdb/telegraf/internal/service"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+const sampleConfig = `
+  # brokers to connect to, the first reachable one is used
+  servers = ["localhost:1883"]
+  # topics to subscribe to, with the + and # wildcards
+  topics = ["telegraf/host01/cpu", "sensors/+/temperature", "devices/#"]
+  # quality of service of the subscriptions: 0 (at most once), 1 (at least
+  # once) or 2 (exactly once)
+  qos = 0
+  # how long to wait for the broker when connecting
+  connection_timeout = "30s"
+
+  # keep the session, and the messages published while disconnected for
+  # qos 1 and 2, across reconnections and restarts. Needs a client_id.
+  persistent_session = false
+  # client id, a random one by default
+  # client_id = "telegraf-host01"
+
+  # username and password to connect with
+  # username = "telegraf"
+  # password = "metricsmetricsmetricsmetrics"
+
+  # connect over TLS, verifying the server with the CA certificate, and
+  # presenting a client certificate
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  # connect over TLS without verifying the server
+  # insecure_skip_verify = false
+
+  # tag the topic of each message is added as, empty to not add it
+  topic_tag = "topic"
+
+  # format of the payloads, "influx" or "json"
+  data_format = "influx"
+  # measurement name and tag keys of json payloads
+  # metric_name = "mqtt_consumer"
+  # tag_keys = ["device"]
+
+  # maximum number of points to buffer between collection intervals
+  point_buffer = 100000
+`
+
+type MQTTConsumer struct {
+	Servers           []string          `doc:"brokers to connect to"`
+	Topics            []string          `doc:"topics to subscribe to, with the + and # wildcards"`
+	QoS               int               `toml:"qos" doc:"quality of service of the subscriptions: 0, 1 or 2"`
+	ConnectionTimeout internal.Duration `doc:"how long to wait for the broker when connecting"`
+	PersistentSession bool              `doc:"keep the session across reconnections, needs a client_id"`
+	ClientID          string            `toml:"client_id" doc:"client id, a random one by default"`
+	Username          string            `doc:"username to connect with"`
+	Password          string            `doc:"password to connect with"`
+
+	TLSCA              string `toml:"tls_ca" doc:"CA certificate the broker must be signed by"`
+	TLSCert            string `toml:"tls_cert" doc:"client certificate to present"`
+	TLSKey             string `toml:"tls_key" doc:"key of the client certificate"`
+	InsecureSkipVerify bool   `doc:"connect over TLS without verifying the broker"`
+
+	TopicTag   string   `doc:"tag the topic of each message is added as, empty to not add it"`
+	DataFormat string   `doc:"format of the payloads: influx or json"`
+	MetricName string   `doc:"measurement name of json payloads"`
+	TagKeys    []string `doc:"json keys to use as tags"`
+
+	PointBuffer int `doc:"maximum number of points to buffer between collection intervals"`
+
+	client *paho.Client
+	parser parsers.Parser
+	buffer service.Buffer
+
+	log *logger.Logger
+}
+
+func NewMQTTConsumer() *MQTTConsumer {
+	return &MQTTConsumer{
+		Servers:           []string{"localhost:1883"},
+		ConnectionTimeout: internal.Duration{Duration: 30 * time.Second},
+		TopicTag:          "topic",
+		MetricName:        "mqtt_consumer",
+		PointBuffer:       service.DefaultPointBuffer,
+	}
+}
+
+func (m *MQTTConsumer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *MQTTConsumer) Description() string {
+	return "Read metrics from MQTT topics"
+}
+
+func (m *MQTTConsumer) SetLogger(log *logger.Logger) {
+	m.log = log
+}
+
+func (m *MQTTConsumer) Start() error {
+	m.buffer.SetMax(m.PointBuffer)
+	if err := m.createParser(); err != nil {
+		return err
+	}
+
+	opts, err := m.createOpts()
+	if err != nil {
+		return err
+	}
+	m.client = paho.NewClient(opts)
+	token := m.client.Connect()
+	if !token.WaitTimeout(m.ConnectionTimeout.Duration) {
+		m.client.Disconnect(0)
+		return fmt.Errorf("mqtt_consumer: timed out connecting to %v",
+			m.Servers)
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("mqtt_consumer: could not connect to %v: %s",
+			m.Servers, err)
+	}
+	m.log.Infof("Connected to %v, topics: %v", m.Servers, m.Topics)
+	return nil
+}
+
+func (m *MQTTConsumer) createParser() error {
+	var err error
+	m.parser, err = parsers.NewParser(&parsers.Config{
+		DataFormat: m.DataFormat,
+		MetricName: m.MetricName,
+		TagKeys:    m.TagKeys,
+	})
+	return err
+}
+
+func (m *MQTTConsumer) createOpts() (*paho.ClientOptions, error) {
+	if len(m.Servers) == 0 {
+		return nil, fmt.Errorf("mqtt_consumer: no servers")
+	}
+	if len(m.Topics) == 0 {
+		return nil, fmt.Errorf("mqtt_consumer: no topics")
+	}
+	for _, topic := range m.Topics {
+		if err := validateTopic(topic); err != nil {
+			return nil, fmt.Errorf("mqtt_consumer: %s", err)
+		}
+	}
+	if m.QoS < 0 || m.QoS > 2 {
+		return nil, fmt.Errorf("mqtt_consumer: qos must be 0, 1 or 2, not %d",
+			m.QoS)
+	}
+	if m.PersistentSession && m.ClientID == "" {
+		return nil, fmt.Errorf("mqtt_consumer: persistent_session needs a " +
+			"client_id")
+	}
+
+	opts := paho.NewClientOptions()
+	clientID := m.ClientID
+	if clientID == "" {
+		clientID = "telegraf-" + internal.RandomString(8)
+	}
+	opts.SetClientID(clientID)
+	opts.SetCleanSession(!m.PersistentSession)
+	if m.Username != "" {
+		opts.SetUsername(m.Username)
+	}
+	if m.Password != "" {
+		opts.SetPassword(m.Password)
+	}
+	opts.SetConnectTimeout(m.ConnectionTimeout.Duration)
+
+	tlsConfig, err := internal.GetClientTLSConfig(
+		m.TLSCA, m.TLSCert, m.TLSKey, m.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	scheme := "tcp"
+	if tlsConfig != nil {
+		scheme = "ssl"
+		opts.SetTLSConfig(tlsConfig)
+	}
+	for _, server := range m.Servers {
+		opts.AddBroker(fmt.Sprintf("%s://%s", scheme, server))
+	}
+
+	// subscribe on every connection, the broker forgets the subscriptions
+	// of clean sessions. Messages of a persistent session that arrive
+	// before subscribing go to the default handler.
+	opts.SetAutoReconnect(true)
+	opts.SetOnConnectHandler(m.subscribe)
+	opts.SetDefaultPublishHandler(m.onMessage)
+	opts.SetConnectionLostHandler(func(_ *paho.Client, err error) {
+		m.log.Warnf("Connection lost, reconnecting: %s", err)
+	})
+	return opts, nil
+}
+
+func (m *MQTTConsumer) subscribe(client *paho.Client) {
+	filters := make(map[string]byte, len(m.Topics))
+	for _, topic := range m.Topics {
+		filters[topic] = byte(m.QoS)
+	}
+	token := client.SubscribeMultiple(filters, m.onMessage)
+	if token.Wait() && token.Error() != nil {
+		m.log.Errorf("Could not subscribe to %v: %s", m.Topics, token.Error())
+	}
+}
+
+// onMessage parses the payload of a message into points, to be added on
+// the next Gather
+func (m *MQTTConsumer) onMessage(_ *paho.Client, msg paho.Message) {
+	points, err := m.parser.Parse(msg.Payload())
+	if err != nil {
+		m.log.Errorf("Could not parse message of topic %s: %s, error: %s",
+			msg.Topic(), string(msg.Payload()), err)
+	}
+	if m.TopicTag != "" {
+		for _, pt := range points {
+			pt.AddTag(m.TopicTag, msg.Topic())
+		}
+	}
+
+	m.buffer.Add(points...)
+}
+
+// validateTopic checks a topic filter: + and # must be whole levels, and #
+// the last level
+func validateTopic(topic string) error {
+	if topic == "" {
+		return fmt.Errorf("empty topic")
+	}
+	levels := strings.Split(topic, "/")
+	for i, level := range levels {
+		if strings.ContainsAny(level, "+#") && len(level) > 1 {
+			return fmt.Errorf("invalid topic %q, wildcards must be whole levels",
+				topic)
+		}
+		if level == "#" && i != len(levels)-1 {
+			return fmt.Errorf("invalid topic %q, # must be the last level", topic)
+		}
+	}
+	return nil
+}
+
+func (m *MQTTConsumer) Stop() {
+	if m.client.IsConnected() {
+		m.client.Disconnect(200)
+	}
+}
+
+// Gather adds the metrics received since the last Gather
+func (m *MQTTConsumer) Gather(acc plugins.Accumulator) error {
+	m.buffer.Gather(acc, m.log)
+	return nil
+}
+
+func init() {
+	plugins.Add("mqtt_consumer", func() plugins.Plugin {
+		return NewMQTTConsumer()
+	})
+}
@@ -0,0 +1,47 @@
+package mqtt_consumer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	paho "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadsMetricsFromMQTT(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	server := testutil.GetLocalHost() + ":1883"
+	topic := fmt.Sprintf("telegraf_test/%d", time.Now().Unix())
+
+	m := NewMQTTConsumer()
+	m.Servers = []string{server}
+	m.Topics = []string{topic + "/+"}
+	m.QoS = 1
+	defer testutil.StartService(t, m)()
+
+	opts := paho.NewClientOptions().AddBroker("tcp://" + server)
+	publisher := paho.NewClient(opts)
+	token := publisher.Connect()
+	require.True(t, token.Wait() && token.Error() == nil)
+	defer publisher.Disconnect(0)
+
+	// the subscription is made right after connecting, asynchronously
+	var acc testutil.Accumulator
+	for i := 0; i < 50 && len(acc.Points) == 0; i++ {
+		token = publisher.Publish(topic+"/cpu", 1, false,
+			"cpu_load_short,host=server01 value=23422.0")
+		require.True(t, token.Wait() && token.Error() == nil)
+		time.Sleep(100 * time.Millisecond)
+		m.Gather(&acc)
+	}
+
+	require.NotEmpty(t, acc.Points)
+	assert.Equal(t, "cpu_load_short", acc.Points[0].Measurement)
+	assert.Equal(t, topic+"/cpu", acc.Points[0].Tags["topic"])
+}
@@ -0,0 +1,137 @@
+package mqtt_consumer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/influxdb/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// message is a paho.Message of a topic
+type message struct {
+	topic   string
+	payload string
+}
+
+func (m *message) Duplicate() bool   { return false }
+func (m *message) Qos() byte         { return 0 }
+func (m *message) Retained() bool    { return false }
+func (m *message) Topic() string     { return m.topic }
+func (m *message) MessageID() uint16 { return 1 }
+func (m *message) Payload() []byte   { return []byte(m.payload) }
+
+// newConsumer returns a consumer of the sensors/# topics
+func newConsumer() *MQTTConsumer {
+	m := NewMQTTConsumer()
+	m.Topics = []string{"sensors/#"}
+	return m
+}
+
+func TestOnMessage(t *testing.T) {
+	m := newConsumer()
+	require.NoError(t, m.createParser())
+	m.onMessage(nil, &message{"sensors/kitchen/temperature",
+		"temperature,unit=c value=21.5\ntemperature,unit=c value=22\n"})
+	m.onMessage(nil, &message{"sensors/kitchen/temperature", "bad"})
+
+	var acc testutil.Accumulator
+	require.NoError(t, m.Gather(&acc))
+	require.Len(t, acc.Points, 2)
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("temperature",
+		map[string]interface{}{"value": 21.5},
+		map[string]string{"unit": "c", "topic": "sensors/kitchen/temperature"}))
+
+	// the buffer is emptied by Gather
+	acc = testutil.Accumulator{}
+	require.NoError(t, m.Gather(&acc))
+	assert.Len(t, acc.Points, 0)
+}
+
+func TestOnMessageJSON(t *testing.T) {
+	m := newConsumer()
+	m.TopicTag = ""
+	m.DataFormat = "json"
+	m.MetricName = "sensor"
+	m.TagKeys = []string{"room"}
+	require.NoError(t, m.createParser())
+	m.onMessage(nil, &message{"sensors/kitchen",
+		`{"room": "kitchen", "temperature": 21.5}`})
+
+	var acc testutil.Accumulator
+	require.NoError(t, m.Gather(&acc))
+	require.Len(t, acc.Points, 1)
+	assert.Equal(t, map[string]string{"room": "kitchen"}, acc.Points[0].Tags)
+	assert.Equal(t, "sensor", acc.Points[0].Measurement)
+}
+
+func TestCreateOpts(t *testing.T) {
+	m := newConsumer()
+	m.Servers = []string{"broker1:1883", "broker2:1883"}
+	m.PersistentSession = true
+	m.ClientID = "telegraf-host01"
+	m.Username = "telegraf"
+	opts, err := m.createOpts()
+	require.NoError(t, err)
+	assert.Equal(t, "telegraf-host01", opts.ClientID)
+	assert.Equal(t, "telegraf", opts.Username)
+	assert.False(t, opts.CleanSession)
+	require.Len(t, opts.Servers, 2)
+	assert.Equal(t, "tcp://broker1:1883", opts.Servers[0].String())
+
+	m = newConsumer()
+	opts, err = m.createOpts()
+	require.NoError(t, err)
+	assert.True(t, opts.CleanSession)
+	assert.Contains(t, opts.ClientID, "telegraf-")
+}
+
+func TestCreateOptsTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mqtt_consumer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	files, err := testutil.NewTLSFiles(dir)
+	require.NoError(t, err)
+
+	m := newConsumer()
+	m.TLSCA = files.Cert
+	m.TLSCert = files.Cert
+	m.TLSKey = files.Key
+	opts, err := m.createOpts()
+	require.NoError(t, err)
+	assert.Equal(t, "ssl://localhost:1883", opts.Servers[0].String())
+	assert.NotNil(t, opts.TLSConfig.RootCAs)
+	assert.Len(t, opts.TLSConfig.Certificates, 1)
+
+	m = newConsumer()
+	m.TLSCA = dir + "/missing.pem"
+	_, err = m.createOpts()
+	assert.Error(t, err)
+}
+
+func TestCreateOptsErrors(t *testing.T) {
+	for _, configure := range []func(*MQTTConsumer){
+		func(m *MQTTConsumer) { m.Servers = nil },
+		func(m *MQTTConsumer) { m.Topics = nil },
+		func(m *MQTTConsumer) { m.Topics = []string{"sensors/#/temperature"} },
+		func(m *MQTTConsumer) { m.QoS = 3 },
+		func(m *MQTTConsumer) { m.PersistentSession = true },
+	} {
+		m := newConsumer()
+		configure(m)
+		_, err := m.createOpts()
+		assert.Error(t, err)
+	}
+}
+
+func TestValidateTopic(t *testing.T) {
+	for _, topic := range []string{"a", "a/b", "+", "#", "a/+/c", "a/#", "/a", "+/+"} {
+		assert.NoError(t, validateTopic(topic), topic)
+	}
+	for _, topic := range []string{"", "a/#/c", "a+", "a/b#", "#/a"} {
+		assert.Error(t, validateTopic(topic), topic)
+	}
+}
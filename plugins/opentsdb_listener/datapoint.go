@@ -0,0 +1,143 @@
+package opentsdb_listener
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdb/influxdb/models"
+)
+
+// datapoint is a data point of the /api/put JSON API. Timestamps and values
+// may be numbers or strings.
+type datapoint struct {
+	Metric    string            `json:"metric"`
+	Timestamp interface{}       `json:"timestamp"`
+	Value     interface{}       `json:"value"`
+	Tags      map[string]string `json:"tags"`
+}
+
+// parseDatapoints decodes the body of /api/put, a data point or an array of
+// data points
+func parseDatapoints(buf []byte) ([]datapoint, error) {
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	if trimmed := bytes.TrimSpace(buf); len(trimmed) > 0 && trimmed[0] == '{' {
+		var dp datapoint
+		if err := decoder.Decode(&dp); err != nil {
+			return nil, err
+		}
+		return []datapoint{dp}, nil
+	}
+	var dps []datapoint
+	if err := decoder.Decode(&dps); err != nil {
+		return nil, err
+	}
+	return dps, nil
+}
+
+// jsonString returns the string of a number or string of a JSON data point
+func jsonString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case json.Number:
+		return v.String(), nil
+	case string:
+		return v, nil
+	case nil:
+		return "", errors.New("missing")
+	}
+	return "", fmt.Errorf("%v is not a number", v)
+}
+
+// point converts a data point to a point, named after the metric up to the
+// last separator, with the rest of the metric as the field. With no
+// separator, or a metric without it, the metric is the measurement and the
+// field is "value".
+func point(
+	metric string,
+	timestamp string,
+	value string,
+	tags map[string]string,
+	separator string,
+) (models.Point, error) {
+	if metric == "" {
+		return nil, errors.New("missing metric")
+	}
+	t, err := parseTimestamp(timestamp)
+	if err != nil {
+		return nil, err
+	}
+	v, err := parseValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	measurement, field := metric, "value"
+	if separator != "" {
+		if i := strings.LastIndex(metric, separator); i > 0 &&
+			i+len(separator) < len(metric) {
+			measurement = metric[:i]
+			field = metric[i+len(separator):]
+		}
+	}
+	return models.NewPoint(measurement, tags,
+		map[string]interface{}{field: v}, t)
+}
+
+// parseTimestamp parses timestamps in seconds or milliseconds since the
+// epoch. As in OpenTSDB, timestamps of more than 10 digits, and those with
+// a decimal point ("1445000000.250"), are in milliseconds.
+func parseTimestamp(s string) (time.Time, error) {
+	if i := strings.Index(s, "."); i >= 0 {
+		if len(s)-i-1 != 3 {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+		}
+		s = s[:i] + s[i+1:]
+	} else if len(s) <= 10 {
+		sec, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || sec < 0 {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+		}
+		return time.Unix(sec, 0), nil
+	}
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || ms < 0 || len(s) > 13 {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+	}
+	return time.Unix(ms/1000, ms%1000*int64(time.Millisecond)), nil
+}
+
+// parseValue parses integers as int64, and other numbers as float64
+func parseValue(s string) (interface{}, error) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("invalid value %q", s)
+	}
+	return f, nil
+}
+
+// parsePut parses the arguments of a telnet put command,
+//     <metric> <timestamp> <value> <tagk1=tagv1> [<tagk2=tagv2>...]
+func parsePut(args []string, separator string) (models.Point, error) {
+	if len(args) < 3 {
+		return nil, errors.New("expected put <metric> <timestamp> <value> " +
+			"<tagk=tagv>...")
+	}
+	tags := make(map[string]string, len(args)-3)
+	for _, tag := range args[3:] {
+		i := strings.Index(tag, "=")
+		if i <= 0 || i == len(tag)-1 {
+			return nil, fmt.Errorf("invalid tag %q", tag)
+		}
+		tags[tag[:i]] = tag[i+1:]
+	}
+	return point(args[0], args[1], args[2], tags, separator)
+}
@@ -0,0 +1,357 @@
+package opentsdb_listener
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb/models"
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/internal/service"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+const sampleConfig = `
+  # address to accept telnet put commands and HTTP /api/put requests on,
+  # both on the same port, like OpenTSDB
+  service_address = ":4242"
+
+  # separator splitting metric names into the measurement, up to the last
+  # separator, and the field. Empty to keep the metric name as the
+  # measurement, with a "value" field.
+  separator = ""
+
+  # maximum number of concurrent connections, 0 for no limit
+  max_connections = 0
+  # close connections that sent nothing for this long, 0 to never close them
+  read_timeout = "0s"
+  # maximum size of an /api/put request body, after gzip decompression
+  max_body_size = "32MB"
+
+  # maximum number of points to buffer between collection intervals
+  point_buffer = 100000
+`
+
+// maxLineSize bounds the size of a telnet command
+const maxLineSize = 64 * 1024
+
+type OpenTSDBListener struct {
+	ServiceAddress string            `doc:"address to accept telnet and HTTP requests on"`
+	Separator      string            `doc:"separator splitting metric names into measurement and field"`
+	MaxConnections int               `doc:"maximum number of concurrent connections, 0 for no limit"`
+	ReadTimeout    internal.Duration `doc:"close connections that sent nothing for this long"`
+	MaxBodySize    internal.Size     `doc:"maximum size of an /api/put request body"`
+	PointBuffer    int               `doc:"maximum number of points to buffer between collection intervals"`
+
+	service.Listener
+	buffer    service.Buffer
+	httpConns *connListener
+	wg        sync.WaitGroup
+
+	log *logger.Logger
+}
+
+func NewOpenTSDBListener() *OpenTSDBListener {
+	return &OpenTSDBListener{
+		ServiceAddress: ":4242",
+		MaxBodySize:    internal.Size{Size: 32 * 1024 * 1024},
+		PointBuffer:    service.DefaultPointBuffer,
+	}
+}
+
+func (o *OpenTSDBListener) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpenTSDBListener) Description() string {
+	return "Accept metrics in the OpenTSDB telnet and HTTP /api/put protocols"
+}
+
+func (o *OpenTSDBListener) SetLogger(log *logger.Logger) {
+	o.log = log
+}
+
+func (o *OpenTSDBListener) Start() error {
+	o.buffer.SetMax(o.PointBuffer)
+	listener, err := net.Listen("tcp", o.ServiceAddress)
+	if err != nil {
+		return err
+	}
+	o.Listen(listener)
+	o.httpConns = newConnListener(listener.Addr())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/put", o.servePut)
+	server := &http.Server{
+		Handler:     mux,
+		ReadTimeout: o.ReadTimeout.Duration,
+		ConnState: func(conn net.Conn, state http.ConnState) {
+			if state == http.StateClosed {
+				o.Untrack(conn)
+			}
+		},
+	}
+
+	o.wg.Add(2)
+	go func() {
+		defer o.wg.Done()
+		server.Serve(o.httpConns)
+	}()
+	go o.accept(listener)
+	o.log.Infof("Listening on %s", listener.Addr())
+	return nil
+}
+
+func (o *OpenTSDBListener) accept(listener net.Listener) {
+	defer o.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !o.Stopping() {
+				o.log.Errorf("Error accepting connection: %s", err)
+			}
+			return
+		}
+
+		pc := &peekedConn{Conn: conn, r: bufio.NewReaderSize(conn, 4096)}
+		if !o.Track(pc, o.MaxConnections) {
+			conn.Close()
+			if o.Stopping() {
+				return
+			}
+			o.log.Warnf("Refused connection from %s, max_connections (%d) "+
+				"reached", conn.RemoteAddr(), o.MaxConnections)
+			continue
+		}
+
+		o.wg.Add(1)
+		go o.handle(pc)
+	}
+}
+
+// handle tells HTTP requests from telnet commands by their first bytes,
+// and hands HTTP connections to the HTTP server, which removes them from
+// the connections once they are closed
+func (o *OpenTSDBListener) handle(conn *peekedConn) {
+	defer o.wg.Done()
+
+	if o.ReadTimeout.Duration > 0 {
+		conn.SetReadDeadline(time.Now().Add(o.ReadTimeout.Duration))
+	}
+	start, _ := conn.r.Peek(4)
+	if !isHTTP(string(start)) || !o.httpConns.deliver(conn) {
+		o.readTelnet(conn)
+		o.Untrack(conn)
+	}
+}
+
+func isHTTP(start string) bool {
+	for _, method := range []string{"GET ", "POST", "PUT ", "HEAD", "OPTI", "DELE"} {
+		if start == method {
+			return true
+		}
+	}
+	return false
+}
+
+// readTelnet runs the telnet commands of a connection until it is closed,
+// or sends exit
+func (o *OpenTSDBListener) readTelnet(conn *peekedConn) {
+	for {
+		if o.ReadTimeout.Duration > 0 {
+			conn.SetReadDeadline(time.Now().Add(o.ReadTimeout.Duration))
+		}
+		line, err := readLine(conn.r)
+		if line != "" && !o.command(conn, line) {
+			return
+		}
+		if err != nil {
+			if err != io.EOF && !o.Stopping() {
+				o.log.Errorf("Error reading from %s: %s", conn.RemoteAddr(), err)
+			}
+			return
+		}
+	}
+}
+
+// command runs a telnet command, and returns false when the connection
+// should be closed. Errors are written back, as OpenTSDB does.
+func (o *OpenTSDBListener) command(conn net.Conn, line string) bool {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return true
+	}
+	switch args[0] {
+	case "put":
+		pt, err := parsePut(args[1:], o.Separator)
+		if err != nil {
+			fmt.Fprintf(conn, "put: illegal argument: %s\n", err)
+			return true
+		}
+		o.buffer.Add(pt)
+	case "version":
+		io.WriteString(conn, "telegraf opentsdb_listener\n")
+	case "exit":
+		return false
+	default:
+		fmt.Fprintf(conn, "unknown command: %s\n", args[0])
+	}
+	return true
+}
+
+// readLine reads a line of up to maxLineSize bytes
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		buf, err := r.ReadSlice('\n')
+		line = append(line, buf...)
+		if len(line) > maxLineSize {
+			return "", fmt.Errorf("line is longer than %d bytes", maxLineSize)
+		}
+		if err != bufio.ErrBufferFull {
+			return strings.TrimSpace(string(line)), err
+		}
+	}
+}
+
+// putError is an error of a data point, in the details of /api/put
+type putError struct {
+	Datapoint datapoint `json:"datapoint"`
+	Error     string    `json:"error"`
+}
+
+func (o *OpenTSDBListener) servePut(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		httpError(w, http.StatusMethodNotAllowed, "put needs a POST")
+		return
+	}
+
+	body := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, "invalid gzip body: "+err.Error())
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	// read one byte more than allowed, to tell a body of the maximum size
+	// from a larger one
+	buf, err := ioutil.ReadAll(io.LimitReader(body, o.MaxBodySize.Size+1))
+	if err != nil {
+		httpError(w, http.StatusBadRequest, "unable to read body: "+err.Error())
+		return
+	}
+	if int64(len(buf)) > o.MaxBodySize.Size {
+		httpError(w, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("body is larger than %d bytes", o.MaxBodySize.Size))
+		return
+	}
+
+	dps, err := parseDatapoints(buf)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+
+	// like OpenTSDB, the valid data points are kept
+	var points []models.Point
+	var errs []putError
+	for _, dp := range dps {
+		pt, err := o.datapoint(dp)
+		if err != nil {
+			errs = append(errs, putError{Datapoint: dp, Error: err.Error()})
+			continue
+		}
+		points = append(points, pt)
+	}
+	o.buffer.Add(points...)
+
+	code := http.StatusNoContent
+	if len(errs) > 0 {
+		code = http.StatusBadRequest
+	}
+	query := r.URL.Query()
+	_, details := query["details"]
+	_, summary := query["summary"]
+	if !details && !summary {
+		if len(errs) > 0 {
+			httpError(w, code, fmt.Sprintf("%d of %d data points failed, "+
+				"first error: %s", len(errs), len(dps), errs[0].Error))
+			return
+		}
+		w.WriteHeader(code)
+		return
+	}
+
+	result := map[string]interface{}{
+		"success": len(points),
+		"failed":  len(errs),
+	}
+	if details {
+		if errs == nil {
+			errs = []putError{}
+		}
+		result["errors"] = errs
+	}
+	if code == http.StatusNoContent {
+		code = http.StatusOK
+	}
+	writeJSON(w, code, result)
+}
+
+func (o *OpenTSDBListener) datapoint(dp datapoint) (models.Point, error) {
+	timestamp, err := jsonString(dp.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("timestamp: %s", err)
+	}
+	value, err := jsonString(dp.Value)
+	if err != nil {
+		return nil, fmt.Errorf("value: %s", err)
+	}
+	return point(dp.Metric, timestamp, value, dp.Tags, o.Separator)
+}
+
+// httpError writes an error response in the format of the OpenTSDB API
+func httpError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]interface{}{
+		"error": map[string]interface{}{"code": code, "message": msg},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	buf, _ := json.Marshal(v)
+	w.Write(buf)
+}
+
+func (o *OpenTSDBListener) Stop() {
+	o.Close()
+	o.httpConns.Close()
+	o.wg.Wait()
+}
+
+// Gather adds the metrics received since the last Gather
+func (o *OpenTSDBListener) Gather(acc plugins.Accumulator) error {
+	o.buffer.Gather(acc, o.log)
+	return nil
+}
+
+func init() {
+	plugins.Add("opentsdb_listener", func() plugins.Plugin {
+		return NewOpenTSDBListener()
+	})
+}
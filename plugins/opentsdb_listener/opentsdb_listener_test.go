@@ -0,0 +1,228 @@
+package opentsdb_listener
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newListener returns a listener on a random port
+func newListener() *OpenTSDBListener {
+	o := NewOpenTSDBListener()
+	o.ServiceAddress = "127.0.0.1:0"
+	return o
+}
+
+func TestTelnet(t *testing.T) {
+	o := newListener()
+	o.Separator = "."
+	defer testutil.StartService(t, o)()
+
+	conn, err := net.Dial("tcp", o.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("put sys.cpu.user 1445000000 42.5 host=web1 cpu=0\n" +
+		"put sys.cpu.nice 1445000000250 3 host=web1\n" +
+		"put sys.cpu.user 1445000000 x host=web1\n" +
+		"version\n" +
+		"stats\n" +
+		"exit\n"))
+	require.NoError(t, err)
+
+	reply, err := ioutil.ReadAll(conn)
+	require.NoError(t, err)
+	assert.Equal(t, "put: illegal argument: invalid value \"x\"\n"+
+		"telegraf opentsdb_listener\n"+
+		"unknown command: stats\n", string(reply))
+
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, o, &acc, 2)
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("sys.cpu",
+		map[string]interface{}{"user": 42.5},
+		map[string]string{"host": "web1", "cpu": "0"}))
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("sys.cpu",
+		map[string]interface{}{"nice": int64(3)},
+		map[string]string{"host": "web1"}))
+	assert.Equal(t, time.Unix(1445000000, 0).UTC(), acc.Points[0].Time.UTC())
+	assert.Equal(t, time.Unix(1445000000, 250000000).UTC(),
+		acc.Points[1].Time.UTC())
+}
+
+func post(t *testing.T, o *OpenTSDBListener, query string, body string) (int, string) {
+	resp, err := http.Post("http://"+o.Addr().String()+"/api/put"+query,
+		"application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	buf, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(buf)
+}
+
+func TestHTTPPut(t *testing.T) {
+	o := newListener()
+	defer testutil.StartService(t, o)()
+
+	code, _ := post(t, o, "", `[
+		{"metric": "sys.cpu.user", "timestamp": 1445000000, "value": 42.5,
+		 "tags": {"host": "web1"}},
+		{"metric": "sys.cpu.nice", "timestamp": "1445000000", "value": "3",
+		 "tags": {"host": "web1"}}
+	]`)
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _ = post(t, o, "", `{"metric": "sys.load", "timestamp": 1445000000,
+		"value": 1, "tags": {"host": "web2"}}`)
+	assert.Equal(t, http.StatusNoContent, code)
+
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, o, &acc, 3)
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("sys.cpu.user",
+		map[string]interface{}{"value": 42.5}, map[string]string{"host": "web1"}))
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("sys.cpu.nice",
+		map[string]interface{}{"value": int64(3)}, map[string]string{"host": "web1"}))
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("sys.load",
+		map[string]interface{}{"value": int64(1)}, map[string]string{"host": "web2"}))
+}
+
+func TestHTTPPutErrors(t *testing.T) {
+	o := newListener()
+	o.MaxBodySize.Size = 1024
+	defer testutil.StartService(t, o)()
+
+	body := `[
+		{"metric": "good", "timestamp": 1445000000, "value": 1},
+		{"metric": "bad", "timestamp": 1445000000, "value": "x"},
+		{"metric": "bad", "value": 1}
+	]`
+	code, resp := post(t, o, "", body)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, resp, `"code":400`)
+
+	code, resp = post(t, o, "?details", body)
+	assert.Equal(t, http.StatusBadRequest, code)
+	var details struct {
+		Success int
+		Failed  int
+		Errors  []putError
+	}
+	require.NoError(t, json.Unmarshal([]byte(resp), &details))
+	assert.Equal(t, 1, details.Success)
+	assert.Equal(t, 2, details.Failed)
+	require.Len(t, details.Errors, 2)
+	assert.Equal(t, `invalid value "x"`, details.Errors[0].Error)
+	assert.Equal(t, "timestamp: missing", details.Errors[1].Error)
+
+	code, resp = post(t, o, "?summary", body[:strings.Index(body, "},")+1]+"]")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `{"failed":0,"success":1}`, resp)
+
+	// the valid data points are kept
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, o, &acc, 3)
+
+	code, _ = post(t, o, "", "not json")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = post(t, o, "", "["+strings.Repeat(" ", 1024)+"]")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+
+	resp2, err := http.Get("http://" + o.Addr().String() + "/api/put")
+	require.NoError(t, err)
+	resp2.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp2.StatusCode)
+}
+
+func TestHTTPPutGzip(t *testing.T) {
+	o := newListener()
+	defer testutil.StartService(t, o)()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`{"metric": "sys.load", "timestamp": 1445000000, "value": 1}`))
+	gz.Close()
+	req, err := http.NewRequest("POST", "http://"+o.Addr().String()+"/api/put",
+		&buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, o, &acc, 1)
+}
+
+func TestMaxConnections(t *testing.T) {
+	o := newListener()
+	o.MaxConnections = 1
+	defer testutil.StartService(t, o)()
+
+	first, err := net.Dial("tcp", o.Addr().String())
+	require.NoError(t, err)
+	defer first.Close()
+	_, err = first.Write([]byte("version\n"))
+	require.NoError(t, err)
+	_, err = bufio.NewReader(first).ReadString('\n')
+	require.NoError(t, err)
+
+	second, err := net.Dial("tcp", o.Addr().String())
+	require.NoError(t, err)
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = second.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	for s, want := range map[string]time.Time{
+		"1445000000":     time.Unix(1445000000, 0),
+		"1445000000250":  time.Unix(1445000000, 250000000),
+		"1445000000.250": time.Unix(1445000000, 250000000),
+		"0":              time.Unix(0, 0),
+	} {
+		got, err := parseTimestamp(s)
+		assert.NoError(t, err, s)
+		assert.True(t, want.Equal(got), "%s: %s", s, got)
+	}
+
+	for _, s := range []string{"", "-1", "1445000000.25", "14450000002500", "now"} {
+		_, err := parseTimestamp(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParsePut(t *testing.T) {
+	pt, err := parsePut(strings.Fields("sys.cpu.user 1445000000 1e3 host=a"), "")
+	require.NoError(t, err)
+	assert.Equal(t, "sys.cpu.user", pt.Name())
+	assert.Equal(t, map[string]interface{}{"value": 1000.0}, map[string]interface{}(pt.Fields()))
+
+	// metrics without the separator, or ending with it, aren't split
+	pt, err = parsePut(strings.Fields("load 1445000000 1"), "_")
+	require.NoError(t, err)
+	assert.Equal(t, "load", pt.Name())
+	pt, err = parsePut(strings.Fields("load_ 1445000000 1"), "_")
+	require.NoError(t, err)
+	assert.Equal(t, "load_", pt.Name())
+
+	for _, put := range []string{
+		"sys.cpu.user 1445000000",
+		"sys.cpu.user 1445000000 1 host",
+		"sys.cpu.user 1445000000 1 =a",
+		"sys.cpu.user 1445000000 NaN host=a",
+	} {
+		_, err := parsePut(strings.Fields(put), "")
+		assert.Error(t, err, put)
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"database/sql"
 	"fmt"
+	"net/url"
+	"regexp"
 	"strings"
 
+	"github.com/influxdb/telegraf/internal/secret"
 	"github.com/influxdb/telegraf/plugins"
 
 	_ "github.com/lib/pq"
@@ -19,11 +22,20 @@ type Server struct {
 
 type Postgresql struct {
 	Servers []*Server
+
+	// SanitizeServerTag strips the password from the server tag. Resolved
+	// secrets are redacted from it either way.
+	SanitizeServerTag bool
 }
 
 var ignoredColumns = map[string]bool{"datid": true, "datname": true, "stats_reset": true}
 
 var sampleConfig = `
+  # Strip the password from the address in the server tag. Changing this
+  # changes the tag, and so starts new series. Secrets referenced with @{...}
+  # are never put in the tag.
+  sanitize_server_tag = true
+
   # specify servers via an array of tables
   [[plugins.postgresql.servers]]
 
@@ -159,7 +171,10 @@ func (p *Postgresql) accRow(row scanner, acc plugins.Accumulator, serv *Server)
 		dbname.WriteString(string(dbnameChars[i]))
 	}
 
-	tags := map[string]string{"server": serv.Address, "db": dbname.String()}
+	tags := map[string]string{
+		"server": p.serverTag(serv.Address),
+		"db":     dbname.String(),
+	}
 
 	for col, val := range columnMap {
 		_, ignore := ignoredColumns[col]
@@ -171,8 +186,33 @@ func (p *Postgresql) accRow(row scanner, acc plugins.Accumulator, serv *Server)
 	return nil
 }
 
+// serverTag returns the server tag of an address
+func (p *Postgresql) serverTag(address string) string {
+	if p.SanitizeServerTag {
+		address = sanitizeAddress(address)
+	}
+	return secret.Redact(address)
+}
+
+var passwordKV = regexp.MustCompile(`\s*password\s*=\s*('(\\.|[^'])*'|\S*)`)
+
+// sanitizeAddress strips the password from a connection string, for the
+// server tag.
+func sanitizeAddress(address string) string {
+	if strings.HasPrefix(address, "postgres://") ||
+		strings.HasPrefix(address, "postgresql://") {
+		u, err := url.Parse(address)
+		if err != nil || u.User == nil {
+			return address
+		}
+		u.User = url.User(u.User.Username())
+		return u.String()
+	}
+	return strings.TrimSpace(passwordKV.ReplaceAllString(address, ""))
+}
+
 func init() {
 	plugins.Add("postgresql", func() plugins.Plugin {
-		return &Postgresql{}
+		return &Postgresql{SanitizeServerTag: true}
 	})
 }
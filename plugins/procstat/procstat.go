@@ -3,7 +3,6 @@ package procstat
 import (
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -11,6 +10,7 @@ import (
 
 	"github.com/shirou/gopsutil/process"
 
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/plugins"
 )
 
@@ -23,6 +23,8 @@ type Specification struct {
 
 type Procstat struct {
 	Specifications []*Specification
+
+	log *logger.Logger
 }
 
 func NewProcstat() *Procstat {
@@ -49,6 +51,10 @@ func (_ *Procstat) Description() string {
 	return "Monitor process cpu and memory usage"
 }
 
+func (p *Procstat) SetLogger(log *logger.Logger) {
+	p.log = log
+}
+
 func (p *Procstat) Gather(acc plugins.Accumulator) error {
 	var wg sync.WaitGroup
 
@@ -58,12 +64,12 @@ func (p *Procstat) Gather(acc plugins.Accumulator) error {
 			defer wg.Done()
 			procs, err := spec.createProcesses()
 			if err != nil {
-				log.Printf("Error: procstat getting process, exe: [%s] pidfile: [%s] pattern: [%s] %s",
+				p.log.Errorf("Getting process, exe: [%s] pidfile: [%s] pattern: [%s] %s",
 					spec.Exe, spec.PidFile, spec.Pattern, err.Error())
 			} else {
 				for _, proc := range procs {
-					p := NewSpecProcessor(spec.Prefix, acc, proc)
-					p.pushMetrics()
+					sp := NewSpecProcessor(spec.Prefix, acc, proc)
+					sp.pushMetrics(p.log)
 				}
 			}
 		}(specification, acc)
@@ -2,10 +2,10 @@ package procstat
 
 import (
 	"fmt"
-	"log"
 
 	"github.com/shirou/gopsutil/process"
 
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/plugins"
 )
 
@@ -44,21 +44,21 @@ func NewSpecProcessor(
 	}
 }
 
-func (p *SpecProcessor) pushMetrics() {
+func (p *SpecProcessor) pushMetrics(log *logger.Logger) {
 	if err := p.pushFDStats(); err != nil {
-		log.Printf("procstat, fd stats not available: %s", err.Error())
+		log.Debugf("fd stats not available: %s", err.Error())
 	}
 	if err := p.pushCtxStats(); err != nil {
-		log.Printf("procstat, ctx stats not available: %s", err.Error())
+		log.Debugf("ctx stats not available: %s", err.Error())
 	}
 	if err := p.pushIOStats(); err != nil {
-		log.Printf("procstat, io stats not available: %s", err.Error())
+		log.Debugf("io stats not available: %s", err.Error())
 	}
 	if err := p.pushCPUStats(); err != nil {
-		log.Printf("procstat, cpu stats not available: %s", err.Error())
+		log.Debugf("cpu stats not available: %s", err.Error())
 	}
 	if err := p.pushMemoryStats(); err != nil {
-		log.Printf("procstat, mem stats not available: %s", err.Error())
+		log.Debugf("mem stats not available: %s", err.Error())
 	}
 }
 
@@ -1,6 +1,10 @@
 package plugins
 
-import "time"
+import (
+	"time"
+
+	"github.com/influxdb/telegraf/internal/logger"
+)
 
 type Accumulator interface {
 	// Create a point with a value, decorating it with tags
@@ -47,6 +51,12 @@ type ServicePlugin interface {
 	Stop()
 }
 
+// LoggerSetter is implemented by plugins that log. The agent hands each
+// plugin instance a logger that tags every line with the plugin's name.
+type LoggerSetter interface {
+	SetLogger(*logger.Logger)
+}
+
 type Creator func() Plugin
 
 var Plugins = map[string]Creator{}
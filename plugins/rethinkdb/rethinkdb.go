@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"sync"
 
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/plugins"
 
 	"gopkg.in/dancannon/gorethink.v1"
@@ -12,6 +13,8 @@ import (
 
 type RethinkDB struct {
 	Servers []string
+
+	log *logger.Logger
 }
 
 var sampleConfig = `
@@ -27,6 +30,10 @@ func (r *RethinkDB) SampleConfig() string {
 	return sampleConfig
 }
 
+func (r *RethinkDB) SetLogger(log *logger.Logger) {
+	r.log = log
+}
+
 func (r *RethinkDB) Description() string {
 	return "Read metrics from one or many RethinkDB servers"
 }
@@ -83,6 +90,7 @@ func (r *RethinkDB) gatherServer(server *Server, acc plugins.Accumulator) error
 	}
 	defer server.session.Close()
 
+	server.log = r.log
 	return server.gatherData(acc)
 }
 
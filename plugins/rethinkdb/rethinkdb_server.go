@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/plugins"
 
 	"gopkg.in/dancannon/gorethink.v1"
@@ -18,6 +19,7 @@ type Server struct {
 	Url          *url.URL
 	session      *gorethink.Session
 	serverStatus serverStatus
+	log          *logger.Logger
 }
 
 func (s *Server) gatherData(acc plugins.Accumulator) error {
@@ -30,7 +32,7 @@ func (s *Server) gatherData(acc plugins.Accumulator) error {
 	}
 
 	if err := s.addClusterStats(acc); err != nil {
-		fmt.Printf("error adding cluster stats, %s\n", err.Error())
+		s.log.Errorf("error adding cluster stats, %s", err.Error())
 		return fmt.Errorf("Error adding cluster stats, %s\n", err.Error())
 	}
 
@@ -0,0 +1,329 @@
+package socket_listener
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/internal/parsers"
+	"github.com/influxdb/telegraf/internal/service"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+const sampleConfig = `
+  # address to listen on: tcp://, udp://, unix:// (stream) or unixgram://,
+  # ie "tcp://:8094", "udp://127.0.0.1:8094" or "unix:///tmp/telegraf.sock"
+  service_address = "tcp://:8094"
+
+  # maximum number of concurrent stream connections, 0 for no limit
+  max_connections = 0
+
+  # size of the socket's receive buffer in bytes, 0 for the OS default
+  read_buffer_size = 0
+
+  # close stream connections that sent nothing for this long, 0 to never
+  # close them
+  read_timeout = "0s"
+
+  # tcp keepalive period, 0 to disable keepalives
+  keep_alive_period = "5m"
+
+  # maximum number of points to buffer between collection intervals
+  point_buffer = 100000
+
+  # certificate and key to accept tcp connections over TLS
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  # only accept clients with a certificate signed by one of these CAs
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  # format of the metrics, "influx", "json" or "grok", one per line
+  data_format = "influx"
+  # measurement name and tag keys of json metrics
+  # metric_name = "socket"
+  # tag_keys = ["host"]
+  # grok patterns of the grok format, see the logparser plugin
+  # patterns = ["%{COMBINED_LOG_FORMAT}"]
+`
+
+// maxLineSize bounds the size of a line read from a stream, and of a
+// datagram
+const maxLineSize = 64 * 1024
+
+type SocketListener struct {
+	ServiceAddress    string            `doc:"address to listen on: tcp://, udp://, unix:// or unixgram://"`
+	MaxConnections    int               `doc:"maximum number of concurrent stream connections, 0 for no limit"`
+	ReadBufferSize    internal.Size     `doc:"size of the socket's receive buffer in bytes, 0 for the OS default"`
+	ReadTimeout       internal.Duration `doc:"close stream connections that sent nothing for this long"`
+	KeepAlivePeriod   internal.Duration `doc:"tcp keepalive period, 0 to disable keepalives"`
+	TLSCert           string            `toml:"tls_cert" doc:"certificate to accept tcp connections over TLS"`
+	TLSKey            string            `toml:"tls_key" doc:"key to accept tcp connections over TLS"`
+	TLSAllowedCACerts []string          `toml:"tls_allowed_cacerts" doc:"CAs client certificates must be signed by"`
+	PointBuffer       int               `doc:"maximum number of points to buffer between collection intervals"`
+
+	DataFormat         string   `doc:"format of the metrics: influx, json or grok"`
+	MetricName         string   `doc:"measurement name of json and grok metrics"`
+	TagKeys            []string `doc:"json keys to use as tags"`
+	Patterns           []string `doc:"grok patterns"`
+	CustomPatterns     string   `doc:"additional grok pattern definitions"`
+	CustomPatternFiles []string `doc:"files of additional grok pattern definitions"`
+
+	service.Listener
+	parser     parsers.Parser
+	buffer     service.Buffer
+	tlsConfig  *tls.Config
+	socketPath string
+	wg         sync.WaitGroup
+
+	log *logger.Logger
+}
+
+func NewSocketListener() *SocketListener {
+	return &SocketListener{
+		ServiceAddress:  "tcp://:8094",
+		KeepAlivePeriod: internal.Duration{Duration: 5 * time.Minute},
+		PointBuffer:     service.DefaultPointBuffer,
+		MetricName:      "socket_listener",
+	}
+}
+
+func (s *SocketListener) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SocketListener) Description() string {
+	return "Accept metrics over TCP, UDP or Unix sockets, in any data format"
+}
+
+func (s *SocketListener) SetLogger(log *logger.Logger) {
+	s.log = log
+}
+
+// SetParser sets the parser lines are parsed with, instead of the parser of
+// data_format. It is used by plugins that listen for their own format, such
+// as graphite.
+func (s *SocketListener) SetParser(parser parsers.Parser) {
+	s.parser = parser
+}
+
+func (s *SocketListener) Start() error {
+	var err error
+	if s.parser == nil {
+		s.parser, err = parsers.NewParser(&parsers.Config{
+			DataFormat:         s.DataFormat,
+			MetricName:         s.MetricName,
+			TagKeys:            s.TagKeys,
+			Patterns:           s.Patterns,
+			CustomPatterns:     s.CustomPatterns,
+			CustomPatternFiles: s.CustomPatternFiles,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	s.buffer.SetMax(s.PointBuffer)
+	u, err := url.Parse(s.ServiceAddress)
+	if err != nil {
+		return fmt.Errorf("socket_listener: invalid address %q: %s",
+			s.ServiceAddress, err)
+	}
+	s.tlsConfig, err = internal.GetServerTLSConfig(
+		s.TLSCert, s.TLSKey, s.TLSAllowedCACerts)
+	if err != nil {
+		return err
+	}
+	if s.tlsConfig != nil && u.Scheme != "tcp" {
+		return fmt.Errorf("socket_listener: TLS is only supported for tcp://")
+	}
+
+	switch u.Scheme {
+	case "udp", "unixgram":
+		address := u.Host
+		if u.Scheme == "unixgram" {
+			address = u.Path
+			s.socketPath = u.Path
+			removeSocket(address)
+		}
+		packets, err := net.ListenPacket(u.Scheme, address)
+		if err != nil {
+			return err
+		}
+		s.ListenPacket(packets)
+		if s.ReadBufferSize.Size > 0 {
+			if rb, ok := packets.(interface {
+				SetReadBuffer(int) error
+			}); ok {
+				if err := rb.SetReadBuffer(int(s.ReadBufferSize.Size)); err != nil {
+					s.log.Warnf("Unable to set read buffer size: %s", err)
+				}
+			}
+		}
+		s.wg.Add(1)
+		go s.readPackets(packets)
+	case "tcp", "unix":
+		address := u.Host
+		if u.Scheme == "unix" {
+			address = u.Path
+			removeSocket(address)
+		}
+		listener, err := net.Listen(u.Scheme, address)
+		if err != nil {
+			return err
+		}
+		s.Listen(listener)
+		s.wg.Add(1)
+		go s.accept(listener)
+	default:
+		return fmt.Errorf("socket_listener: unknown scheme %q, must be tcp, "+
+			"udp, unix or unixgram", u.Scheme)
+	}
+	s.log.Infof("Listening on %s://%s", u.Scheme, s.Addr())
+	return nil
+}
+
+func (s *SocketListener) readPackets(packets net.PacketConn) {
+	defer s.wg.Done()
+	buf := make([]byte, maxLineSize)
+	for {
+		n, _, err := packets.ReadFrom(buf)
+		if err != nil {
+			if !s.Stopping() {
+				s.log.Errorf("Error reading packet: %s", err)
+			}
+			return
+		}
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			s.parse(line)
+		}
+	}
+}
+
+func (s *SocketListener) accept(listener net.Listener) {
+	defer s.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !s.Stopping() {
+				s.log.Errorf("Error accepting connection: %s", err)
+			}
+			return
+		}
+
+		if tc, ok := conn.(*net.TCPConn); ok {
+			if s.KeepAlivePeriod.Duration > 0 {
+				tc.SetKeepAlive(true)
+				tc.SetKeepAlivePeriod(s.KeepAlivePeriod.Duration)
+			} else {
+				tc.SetKeepAlive(false)
+			}
+			if s.ReadBufferSize.Size > 0 {
+				tc.SetReadBuffer(int(s.ReadBufferSize.Size))
+			}
+		}
+		if s.tlsConfig != nil {
+			conn = tls.Server(conn, s.tlsConfig)
+		}
+
+		if !s.Track(conn, s.MaxConnections) {
+			conn.Close()
+			if s.Stopping() {
+				return
+			}
+			s.log.Warnf("Refused connection from %s, max_connections (%d) "+
+				"reached", conn.RemoteAddr(), s.MaxConnections)
+			continue
+		}
+
+		s.wg.Add(1)
+		go s.readStream(conn)
+	}
+}
+
+func (s *SocketListener) readStream(conn net.Conn) {
+	defer s.wg.Done()
+	defer s.Untrack(conn)
+
+	r := bufio.NewReaderSize(conn, 4096)
+	for {
+		if s.ReadTimeout.Duration > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.ReadTimeout.Duration))
+		}
+		line, err := readLine(r)
+		s.parse(line)
+		if err != nil {
+			if err != io.EOF && !s.Stopping() {
+				s.log.Errorf("Error reading from %s: %s", conn.RemoteAddr(), err)
+			}
+			return
+		}
+	}
+}
+
+// readLine reads a line of up to maxLineSize bytes
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		buf, err := r.ReadSlice('\n')
+		line = append(line, buf...)
+		if len(line) > maxLineSize {
+			return "", fmt.Errorf("line is longer than %d bytes", maxLineSize)
+		}
+		if err != bufio.ErrBufferFull {
+			return string(line), err
+		}
+	}
+}
+
+// parse parses a line into points, to be added on the next Gather
+func (s *SocketListener) parse(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	points, err := s.parser.Parse([]byte(line))
+	if err != nil {
+		s.log.Errorf("Could not parse metrics: %s, error: %s", line, err)
+		return
+	}
+
+	s.buffer.Add(points...)
+}
+
+func (s *SocketListener) Stop() {
+	s.Close()
+	s.wg.Wait()
+
+	if s.socketPath != "" {
+		removeSocket(s.socketPath)
+	}
+}
+
+// removeSocket removes the unix socket left at path by a previous run. Any
+// other kind of file is left alone, and binding to it fails.
+func removeSocket(path string) {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+}
+
+// Gather adds the metrics received since the last Gather
+func (s *SocketListener) Gather(acc plugins.Accumulator) error {
+	s.buffer.Gather(acc, s.log)
+	return nil
+}
+
+func init() {
+	plugins.Add("socket_listener", func() plugins.Plugin {
+		return NewSocketListener()
+	})
+}
@@ -0,0 +1,181 @@
+package socket_listener
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func send(t *testing.T, conn net.Conn, data string) {
+	_, err := conn.Write([]byte(data))
+	require.NoError(t, err)
+}
+
+func TestSocketListenerTCP(t *testing.T) {
+	s := NewSocketListener()
+	s.ServiceAddress = "tcp://127.0.0.1:0"
+	defer testutil.StartService(t, s)()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	send(t, conn, "cpu,host=a usage=1.5\nmem free=10i\nbad line\n")
+	conn.Close()
+
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, s, &acc, 2)
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("cpu",
+		map[string]interface{}{"usage": 1.5}, map[string]string{"host": "a"}))
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("mem",
+		map[string]interface{}{"free": int64(10)}, nil))
+}
+
+func TestSocketListenerUDP(t *testing.T) {
+	s := NewSocketListener()
+	s.ServiceAddress = "udp://127.0.0.1:0"
+	s.ReadBufferSize.Size = 1024 * 1024
+	s.DataFormat = "json"
+	s.MetricName = "app"
+	s.TagKeys = []string{"host"}
+	defer testutil.StartService(t, s)()
+
+	conn, err := net.Dial("udp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	send(t, conn, `{"host": "a", "load": 0.5}`)
+
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, s, &acc, 1)
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("app",
+		map[string]interface{}{"load": 0.5}, map[string]string{"host": "a"}))
+}
+
+func TestSocketListenerUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket_listener")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, scheme := range []string{"unix", "unixgram"} {
+		path := filepath.Join(dir, scheme+".sock")
+		s := NewSocketListener()
+		s.ServiceAddress = scheme + "://" + path
+		testutil.StartService(t, s)
+
+		conn, err := net.Dial(scheme, path)
+		require.NoError(t, err)
+		send(t, conn, "cpu usage=1\n")
+		conn.Close()
+
+		var acc testutil.Accumulator
+		testutil.GatherUntil(t, s, &acc, 1)
+		s.Stop()
+
+		_, err = os.Stat(path)
+		assert.True(t, os.IsNotExist(err), "%s socket is removed", scheme)
+	}
+}
+
+func TestSocketListenerUnixKeepsFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket_listener")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// a regular file at the socket path isn't removed
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("keep\n"), 0644))
+	s := NewSocketListener()
+	s.ServiceAddress = "unix://" + path
+	assert.Error(t, s.Start())
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "keep\n", string(data))
+}
+
+func TestSocketListenerMaxConnections(t *testing.T) {
+	s := NewSocketListener()
+	s.ServiceAddress = "tcp://127.0.0.1:0"
+	s.MaxConnections = 1
+	defer testutil.StartService(t, s)()
+
+	first, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer first.Close()
+	send(t, first, "cpu usage=1\n")
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, s, &acc, 1)
+
+	// the second connection is closed right away
+	second, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = second.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.False(t, isTimeout(err), "connection was closed, not timed out")
+}
+
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
+
+func TestSocketListenerTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket_listener")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	files, err := testutil.NewTLSFiles(dir)
+	require.NoError(t, err)
+
+	s := NewSocketListener()
+	s.ServiceAddress = "tcp://127.0.0.1:0"
+	s.TLSCert = files.Cert
+	s.TLSKey = files.Key
+	s.TLSAllowedCACerts = []string{files.Cert}
+	defer testutil.StartService(t, s)()
+
+	conn, err := tls.Dial("tcp", s.Addr().String(), files.ClientConfig)
+	require.NoError(t, err)
+	send(t, conn, "cpu usage=1\n")
+	conn.Close()
+
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, s, &acc, 1)
+
+	// clients without a certificate are refused
+	conn, err = tls.Dial("tcp", s.Addr().String(),
+		&tls.Config{RootCAs: files.ClientConfig.RootCAs})
+	if err == nil {
+		_, err = conn.Write([]byte("cpu usage=2\n"))
+		if err == nil {
+			_, err = conn.Read(make([]byte, 1))
+		}
+		conn.Close()
+	}
+	assert.Error(t, err)
+}
+
+func TestSocketListenerBadConfig(t *testing.T) {
+	s := NewSocketListener()
+	s.ServiceAddress = "http://127.0.0.1:0"
+	assert.Error(t, s.Start())
+
+	s = NewSocketListener()
+	s.ServiceAddress = "udp://127.0.0.1:0"
+	s.TLSCert = "cert.pem"
+	s.TLSKey = "key.pem"
+	assert.Error(t, s.Start())
+
+	s = NewSocketListener()
+	s.ServiceAddress = "tcp://127.0.0.1:0"
+	s.DataFormat = "xml"
+	assert.Error(t, s.Start())
+}
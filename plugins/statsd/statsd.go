@@ -3,8 +3,8 @@ package statsd
 import (
 	"errors"
 	"fmt"
-	"log"
 	"net"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -12,11 +12,12 @@ import (
 
 	"github.com/influxdb/influxdb/services/graphite"
 
+	"github.com/influxdb/telegraf/internal/logger"
 	"github.com/influxdb/telegraf/plugins"
 )
 
-var dropwarn = "ERROR: Message queue full. Discarding line [%s] " +
-	"You may want to increase allowed_pending_messages in the config\n"
+var dropwarn = "Message queue full. Discarding line [%s] " +
+	"You may want to increase allowed_pending_messages in the config"
 
 type Statsd struct {
 	// Address & Port to serve from
@@ -50,6 +51,8 @@ type Statsd struct {
 
 	// bucket -> influx templates
 	Templates []string
+
+	log *logger.Logger
 }
 
 func NewStatsd() *Statsd {
@@ -139,6 +142,10 @@ func (_ *Statsd) SampleConfig() string {
 	return sampleConfig
 }
 
+func (s *Statsd) SetLogger(log *logger.Logger) {
+	s.log = log
+}
+
 func (s *Statsd) Gather(acc plugins.Accumulator) error {
 	s.Lock()
 	defer s.Unlock()
@@ -195,7 +202,7 @@ func (s *Statsd) Start() error {
 	go s.udpListen()
 	// Start the line parser
 	go s.parser()
-	log.Printf("Started the statsd service on %s\n", s.ServiceAddress)
+	s.log.Infof("Started the statsd service on %s", s.ServiceAddress)
 	return nil
 }
 
@@ -204,10 +211,11 @@ func (s *Statsd) udpListen() error {
 	address, _ := net.ResolveUDPAddr("udp", s.ServiceAddress)
 	listener, err := net.ListenUDP("udp", address)
 	if err != nil {
-		log.Fatalf("ERROR: ListenUDP - %s", err)
+		s.log.Errorf("ListenUDP - %s", err)
+		os.Exit(1)
 	}
 	defer listener.Close()
-	log.Println("Statsd listener listening on: ", listener.LocalAddr().String())
+	s.log.Infof("Statsd listener listening on: %s", listener.LocalAddr().String())
 
 	for {
 		select {
@@ -217,7 +225,7 @@ func (s *Statsd) udpListen() error {
 			buf := make([]byte, 1024)
 			n, _, err := listener.ReadFromUDP(buf)
 			if err != nil {
-				log.Printf("ERROR: %s\n", err.Error())
+				s.log.Errorf("%s", err.Error())
 			}
 
 			lines := strings.Split(string(buf[:n]), "\n")
@@ -227,7 +235,7 @@ func (s *Statsd) udpListen() error {
 					select {
 					case s.in <- line:
 					default:
-						log.Printf(dropwarn, line)
+						s.log.Warnf(dropwarn, line)
 					}
 				}
 			}
@@ -257,7 +265,7 @@ func (s *Statsd) parseStatsdLine(line string) error {
 	// Validate splitting the line on ":"
 	bits := strings.Split(line, ":")
 	if len(bits) < 2 {
-		log.Printf("Error: splitting ':', Unable to parse metric: %s\n", line)
+		s.log.Errorf("Splitting ':', Unable to parse metric: %s", line)
 		return errors.New("Error Parsing statsd line")
 	}
 
@@ -273,22 +281,22 @@ func (s *Statsd) parseStatsdLine(line string) error {
 		// Validate splitting the bit on "|"
 		pipesplit := strings.Split(bit, "|")
 		if len(pipesplit) < 2 {
-			log.Printf("Error: splitting '|', Unable to parse metric: %s\n", line)
+			s.log.Errorf("Splitting '|', Unable to parse metric: %s", line)
 			return errors.New("Error Parsing statsd line")
 		} else if len(pipesplit) > 2 {
 			sr := pipesplit[2]
-			errmsg := "Error: parsing sample rate, %s, it must be in format like: " +
-				"@0.1, @0.5, etc. Ignoring sample rate for line: %s\n"
+			errmsg := "Parsing sample rate, %s, it must be in format like: " +
+				"@0.1, @0.5, etc. Ignoring sample rate for line: %s"
 			if strings.Contains(sr, "@") && len(sr) > 1 {
 				samplerate, err := strconv.ParseFloat(sr[1:], 64)
 				if err != nil {
-					log.Printf(errmsg, err.Error(), line)
+					s.log.Errorf(errmsg, err.Error(), line)
 				} else {
 					// sample rate successfully parsed
 					m.samplerate = samplerate
 				}
 			} else {
-				log.Printf(errmsg, "", line)
+				s.log.Errorf(errmsg, "", line)
 			}
 		}
 
@@ -297,14 +305,14 @@ func (s *Statsd) parseStatsdLine(line string) error {
 		case "g", "c", "s", "ms", "h":
 			m.mtype = pipesplit[1]
 		default:
-			log.Printf("Error: Statsd Metric type %s unsupported", pipesplit[1])
+			s.log.Errorf("Statsd Metric type %s unsupported", pipesplit[1])
 			return errors.New("Error Parsing statsd line")
 		}
 
 		// Parse the value
 		if strings.ContainsAny(pipesplit[0], "-+") {
 			if m.mtype != "g" {
-				log.Printf("Error: +- values are only supported for gauges: %s\n", line)
+				s.log.Errorf("+- values are only supported for gauges: %s", line)
 				return errors.New("Error Parsing statsd line")
 			}
 			m.additive = true
@@ -314,14 +322,14 @@ func (s *Statsd) parseStatsdLine(line string) error {
 		case "g", "ms", "h":
 			v, err := strconv.ParseFloat(pipesplit[0], 64)
 			if err != nil {
-				log.Printf("Error: parsing value to float64: %s\n", line)
+				s.log.Errorf("Parsing value to float64: %s", line)
 				return errors.New("Error Parsing statsd line")
 			}
 			m.floatvalue = v
 		case "c", "s":
 			v, err := strconv.ParseInt(pipesplit[0], 10, 64)
 			if err != nil {
-				log.Printf("Error: parsing value to int64: %s\n", line)
+				s.log.Errorf("Parsing value to int64: %s", line)
 				return errors.New("Error Parsing statsd line")
 			}
 			// If a sample rate is given with a counter, divide value by the rate
@@ -484,7 +492,7 @@ func (s *Statsd) aggregate(m metric) {
 func (s *Statsd) Stop() {
 	s.Lock()
 	defer s.Unlock()
-	log.Println("Stopping the statsd service")
+	s.log.Infof("Stopping the statsd service")
 	close(s.done)
 	close(s.in)
 }
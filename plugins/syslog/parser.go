@@ -0,0 +1,298 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxMessageSize bounds the size of a message read from a stream
+const maxMessageSize = 64 * 1024
+
+// maxLengthDigits bounds the length prefix of octet-counted messages
+var maxLengthDigits = len(strconv.Itoa(maxMessageSize))
+
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console",
+	"solaris-cron", "local0", "local1", "local2", "local3", "local4",
+	"local5", "local6", "local7",
+}
+
+var severities = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+// message is a parsed syslog message, empty strings are values that were
+// missing or nil (-) in the message.
+type message struct {
+	facility  int
+	severity  int
+	version   int
+	timestamp time.Time
+	hostname  string
+	appname   string
+	procid    string
+	msgid     string
+	// structured is the structured data of the message, by element ID and
+	// parameter name
+	structured map[string]map[string]string
+	message    string
+}
+
+// parseMessage parses an RFC 5424 message, or an RFC 3164 (BSD) message
+// if it has no version.
+func parseMessage(buf []byte) (*message, error) {
+	buf = bytes.TrimRight(buf, "\r\n\x00")
+	if len(buf) < 3 || buf[0] != '<' {
+		return nil, errors.New("message doesn't start with a priority")
+	}
+	end := bytes.IndexByte(buf, '>')
+	if end < 2 || end > 4 {
+		return nil, errors.New("invalid priority")
+	}
+	for _, c := range buf[1:end] {
+		if c < '0' || c > '9' {
+			return nil, fmt.Errorf("invalid priority %q", buf[1:end])
+		}
+	}
+	pri, err := strconv.Atoi(string(buf[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return nil, fmt.Errorf("invalid priority %q", buf[1:end])
+	}
+
+	m := &message{facility: pri / 8, severity: pri % 8}
+	rest := string(buf[end+1:])
+	if len(rest) > 1 && rest[0] >= '1' && rest[0] <= '9' {
+		if i := strings.IndexByte(rest, ' '); i > 0 {
+			if version, err := strconv.Atoi(rest[:i]); err == nil {
+				m.version = version
+				return m, m.parseRFC5424(rest[i+1:])
+			}
+		}
+	}
+	m.parseRFC3164(rest)
+	return m, nil
+}
+
+// parseRFC5424 parses the part of a message after its version:
+//     TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func (m *message) parseRFC5424(s string) error {
+	var header [5]string
+	for i := range header {
+		j := strings.IndexByte(s, ' ')
+		if j < 0 {
+			return errors.New("message header is too short")
+		}
+		header[i], s = nilValue(s[:j]), s[j+1:]
+	}
+
+	if header[0] != "" {
+		t, err := time.Parse(time.RFC3339Nano, header[0])
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %q", header[0])
+		}
+		m.timestamp = t
+	}
+	m.hostname, m.appname, m.procid, m.msgid =
+		header[1], header[2], header[3], header[4]
+
+	rest, err := m.parseStructuredData(s)
+	if err != nil {
+		return err
+	}
+	rest = strings.TrimPrefix(rest, " ")
+	m.message = strings.TrimPrefix(rest, "\xef\xbb\xbf")
+	return nil
+}
+
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// parseStructuredData parses the structured data elements at the start of
+// s, ie,
+//     [exampleSDID@32473 iut="3" eventSource="Application"]
+// and returns the rest of s.
+func (m *message) parseStructuredData(s string) (string, error) {
+	if strings.HasPrefix(s, "-") {
+		return s[1:], nil
+	}
+	if !strings.HasPrefix(s, "[") {
+		return "", errors.New("invalid structured data")
+	}
+
+	m.structured = make(map[string]map[string]string)
+	for strings.HasPrefix(s, "[") {
+		s = s[1:]
+		end := strings.IndexAny(s, " ]")
+		if end <= 0 {
+			return "", errors.New("invalid structured data element")
+		}
+		id := s[:end]
+		params := make(map[string]string)
+		m.structured[id] = params
+		s = s[end:]
+
+		for {
+			s = strings.TrimLeft(s, " ")
+			if strings.HasPrefix(s, "]") {
+				s = s[1:]
+				break
+			}
+			eq := strings.Index(s, `="`)
+			if eq <= 0 {
+				return "", fmt.Errorf("invalid parameter in %s", id)
+			}
+			name := s[:eq]
+			s = s[eq+2:]
+
+			// the value ends at the first unescaped quote, \", \\ and \]
+			// are escapes
+			var value bytes.Buffer
+			closed := false
+			for i := 0; i < len(s); i++ {
+				c := s[i]
+				if c == '\\' && i+1 < len(s) &&
+					(s[i+1] == '"' || s[i+1] == '\\' || s[i+1] == ']') {
+					value.WriteByte(s[i+1])
+					i++
+					continue
+				}
+				if c == '"' {
+					s = s[i+1:]
+					closed = true
+					break
+				}
+				value.WriteByte(c)
+			}
+			if !closed {
+				return "", fmt.Errorf("unterminated parameter %s in %s", name, id)
+			}
+			params[name] = value.String()
+		}
+	}
+	return s, nil
+}
+
+// parseRFC3164 parses the part of a BSD message after its priority:
+//     Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+// Messages that don't follow the format are kept whole as the message.
+func (m *message) parseRFC3164(s string) {
+	const layout = "Jan _2 15:04:05"
+	if len(s) < len(layout)+1 || s[len(layout)] != ' ' {
+		m.message = s
+		return
+	}
+	t, err := time.Parse(layout, s[:len(layout)])
+	if err != nil {
+		m.message = s
+		return
+	}
+	// BSD timestamps have no year, they are in the current one
+	now := time.Now()
+	m.timestamp = time.Date(now.Year(), t.Month(), t.Day(), t.Hour(),
+		t.Minute(), t.Second(), 0, time.Local)
+	if m.timestamp.After(now.AddDate(0, 1, 0)) {
+		// a message of December received in January
+		m.timestamp = m.timestamp.AddDate(-1, 0, 0)
+	}
+	s = s[len(layout)+1:]
+
+	if i := strings.IndexByte(s, ' '); i > 0 {
+		m.hostname, s = s[:i], s[i+1:]
+	}
+
+	// the tag ends at the first character that isn't alphanumeric, and
+	// is usually followed by the pid in brackets and a colon
+	i := strings.IndexAny(s, "[: ")
+	if i > 0 && i <= 48 {
+		m.appname, s = s[:i], s[i:]
+		if strings.HasPrefix(s, "[") {
+			if j := strings.IndexByte(s, ']'); j > 0 {
+				m.procid, s = s[1:j], s[j+1:]
+			}
+		}
+		s = strings.TrimPrefix(s, ":")
+	}
+	m.message = strings.TrimPrefix(s, " ")
+}
+
+// readFrame reads a message from a stream, with octet-counting framing
+// ("<length> <message>") or non-transparent framing (messages ending with a
+// newline). Framing "auto" picks one per message, from whether it starts
+// with a digit.
+func readFrame(r *bufio.Reader, framing string) ([]byte, error) {
+	if framing == "auto" {
+		first, err := r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		framing = "non-transparent"
+		if first[0] >= '0' && first[0] <= '9' {
+			framing = "octet-counting"
+		}
+	}
+
+	if framing == "octet-counting" {
+		length, err := readLength(r)
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(string(length))
+		if err != nil || n <= 0 || n > maxMessageSize {
+			return nil, fmt.Errorf("invalid message length %q", length)
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf, nil
+	}
+
+	var buf []byte
+	for {
+		line, err := r.ReadSlice('\n')
+		buf = append(buf, line...)
+		if err == bufio.ErrBufferFull {
+			if len(buf) > maxMessageSize {
+				return nil, fmt.Errorf("message is longer than %d bytes",
+					maxMessageSize)
+			}
+			continue
+		}
+		if err == io.EOF && len(bytes.TrimSpace(buf)) > 0 {
+			return buf, nil
+		}
+		return buf, err
+	}
+}
+
+// readLength reads the length prefix of an octet-counted message, up to the
+// space after it, skipping the newlines some senders add between messages.
+func readLength(r *bufio.Reader) ([]byte, error) {
+	var length []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case c == ' ' && len(length) > 0:
+			return length, nil
+		case (c == '\r' || c == '\n') && len(length) == 0:
+			continue
+		case c < '0' || c > '9' || len(length) == maxLengthDigits:
+			return nil, fmt.Errorf("invalid message length %q", append(length, c))
+		}
+		length = append(length, c)
+	}
+}
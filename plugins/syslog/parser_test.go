@@ -0,0 +1,121 @@
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRFC5424(t *testing.T) {
+	m, err := parseMessage([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com ` +
+		`evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Appli\"cation"]` +
+		`[examplePriority@32473 class="high"] ` + "\xef\xbb\xbf" + `An application event`))
+	require.NoError(t, err)
+
+	assert.Equal(t, 20, m.facility)
+	assert.Equal(t, 5, m.severity)
+	assert.Equal(t, 1, m.version)
+	assert.Equal(t, time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+		m.timestamp.UTC())
+	assert.Equal(t, "mymachine.example.com", m.hostname)
+	assert.Equal(t, "evntslog", m.appname)
+	assert.Equal(t, "", m.procid)
+	assert.Equal(t, "ID47", m.msgid)
+	assert.Equal(t, map[string]map[string]string{
+		"exampleSDID@32473":     {"iut": "3", "eventSource": `Appli"cation`},
+		"examplePriority@32473": {"class": "high"},
+	}, m.structured)
+	assert.Equal(t, "An application event", m.message)
+}
+
+func TestParseRFC5424NoData(t *testing.T) {
+	m, err := parseMessage([]byte("<34>1 - - su 8710 - -"))
+	require.NoError(t, err)
+	assert.True(t, m.timestamp.IsZero())
+	assert.Equal(t, "", m.hostname)
+	assert.Equal(t, "su", m.appname)
+	assert.Equal(t, "8710", m.procid)
+	assert.Nil(t, m.structured)
+	assert.Equal(t, "", m.message)
+}
+
+func TestParseRFC3164(t *testing.T) {
+	m, err := parseMessage([]byte("<34>Oct 11 22:14:15 mymachine su[123]: " +
+		"'su root' failed for lonvick on /dev/pts/8\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, m.facility)
+	assert.Equal(t, 2, m.severity)
+	assert.Equal(t, 0, m.version)
+	assert.Equal(t, time.October, m.timestamp.Month())
+	assert.Equal(t, 22, m.timestamp.Hour())
+	assert.Equal(t, "mymachine", m.hostname)
+	assert.Equal(t, "su", m.appname)
+	assert.Equal(t, "123", m.procid)
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", m.message)
+
+	// messages that don't follow the format are kept whole
+	m, err = parseMessage([]byte("<13>link down on port 3"))
+	require.NoError(t, err)
+	assert.Equal(t, "link down on port 3", m.message)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, msg := range []string{
+		"",
+		"no priority",
+		"<>1 - - - - - -",
+		"<192>1 - - - - - -",
+		"<-1>1 - - - - - -",
+		"<-0>1 - - - - - -",
+		"<+1>1 - - - - - -",
+		"< 1>1 - - - - - -",
+		"<1a>1 - - - - - -",
+		"<34>1 -",
+		"<34>1 not-a-time - - - - -",
+		"<34>1 - - - - - [id",
+		`<34>1 - - - - - [id a="1]`,
+		"<34>1 - - - - - data",
+	} {
+		_, err := parseMessage([]byte(msg))
+		assert.Error(t, err, msg)
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader(
+		"12 <13>1 - - -\n" + // octet counting, with a newline in the message
+			"<13>line one\n" +
+			"9 <13>1 - -" +
+			"<13>last line"))
+
+	for _, want := range []string{
+		"<13>1 - - -\n",
+		"<13>line one\n",
+		"<13>1 - -",
+		"<13>last line",
+	} {
+		buf, err := readFrame(r, "auto")
+		require.NoError(t, err)
+		assert.Equal(t, want, string(buf))
+	}
+	_, err := readFrame(r, "auto")
+	assert.Equal(t, io.EOF, err)
+
+	for _, frame := range []string{
+		"abc <13>",
+		"-1 <13>",
+		"99999 <13>", // longer than maxMessageSize
+		"123456789 <13>",
+		strings.Repeat("1", 1024*1024), // a length that never ends
+	} {
+		_, err = readFrame(bufio.NewReader(strings.NewReader(frame)),
+			"octet-counting")
+		assert.Error(t, err, "%.16s", frame)
+	}
+}
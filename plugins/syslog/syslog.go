@@ -0,0 +1,256 @@
+package syslog
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb/models"
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/internal/service"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+const sampleConfig = `
+  # address to listen on, udp://, tcp:// or tls:// followed by host:port
+  address = "udp://:514"
+
+  # framing of messages on tcp and tls streams, "octet-counting" (RFC 5425),
+  # "non-transparent" (one message per line) or "auto" to detect it
+  framing = "auto"
+
+  # close tcp and tls connections that sent nothing for this long, 0 to
+  # never close them
+  read_timeout = "0s"
+
+  # maximum number of messages to buffer between collection intervals
+  point_buffer = 100000
+
+  # certificate and key of tls:// addresses
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  # only accept clients with a certificate signed by one of these CAs
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+`
+
+type Syslog struct {
+	Address           string            `doc:"address to listen on: udp://, tcp:// or tls:// followed by host:port"`
+	Framing           string            `doc:"framing of streams: octet-counting, non-transparent or auto"`
+	ReadTimeout       internal.Duration `doc:"close stream connections that sent nothing for this long"`
+	TLSCert           string            `toml:"tls_cert" doc:"certificate of tls:// addresses"`
+	TLSKey            string            `toml:"tls_key" doc:"key of tls:// addresses"`
+	TLSAllowedCACerts []string          `toml:"tls_allowed_cacerts" doc:"CAs client certificates must be signed by"`
+	PointBuffer       int               `doc:"maximum number of messages to buffer between collection intervals"`
+
+	service.Listener
+	buffer service.Buffer
+	wg     sync.WaitGroup
+
+	log *logger.Logger
+}
+
+func NewSyslog() *Syslog {
+	return &Syslog{
+		Address:     "udp://:514",
+		Framing:     "auto",
+		PointBuffer: service.DefaultPointBuffer,
+	}
+}
+
+func (s *Syslog) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Syslog) Description() string {
+	return "Accept syslog messages over UDP, TCP or TLS (RFC 5424 and RFC 3164)"
+}
+
+func (s *Syslog) SetLogger(log *logger.Logger) {
+	s.log = log
+}
+
+func (s *Syslog) Start() error {
+	switch s.Framing {
+	case "auto", "octet-counting", "non-transparent":
+	default:
+		return fmt.Errorf("syslog: unknown framing %q", s.Framing)
+	}
+
+	u, err := url.Parse(s.Address)
+	if err != nil {
+		return fmt.Errorf("syslog: invalid address %q: %s", s.Address, err)
+	}
+	s.buffer.SetMax(s.PointBuffer)
+	tlsConfig, err := internal.GetServerTLSConfig(
+		s.TLSCert, s.TLSKey, s.TLSAllowedCACerts)
+	if err != nil {
+		return err
+	}
+
+	var listener net.Listener
+	switch u.Scheme {
+	case "udp":
+		packets, err := net.ListenPacket("udp", u.Host)
+		if err != nil {
+			return err
+		}
+		s.ListenPacket(packets)
+		s.wg.Add(1)
+		go s.readPackets(packets)
+		s.log.Infof("Listening on udp://%s", s.Addr())
+		return nil
+	case "tcp":
+		listener, err = net.Listen("tcp", u.Host)
+	case "tls":
+		if tlsConfig == nil {
+			return fmt.Errorf("syslog: %s needs tls_cert and tls_key", s.Address)
+		}
+		listener, err = tls.Listen("tcp", u.Host, tlsConfig)
+	default:
+		return fmt.Errorf("syslog: unknown scheme %q, must be udp, tcp or tls",
+			u.Scheme)
+	}
+	if err != nil {
+		return err
+	}
+	s.Listen(listener)
+	s.wg.Add(1)
+	go s.accept(listener)
+	s.log.Infof("Listening on %s://%s", u.Scheme, s.Addr())
+	return nil
+}
+
+func (s *Syslog) readPackets(packets net.PacketConn) {
+	defer s.wg.Done()
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, _, err := packets.ReadFrom(buf)
+		if err != nil {
+			if !s.Stopping() {
+				s.log.Errorf("Error reading packet: %s", err)
+			}
+			return
+		}
+		s.add(buf[:n])
+	}
+}
+
+func (s *Syslog) accept(listener net.Listener) {
+	defer s.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !s.Stopping() {
+				s.log.Errorf("Error accepting connection: %s", err)
+			}
+			return
+		}
+
+		if !s.Track(conn, 0) {
+			conn.Close()
+			return
+		}
+		s.wg.Add(1)
+		go s.readStream(conn)
+	}
+}
+
+func (s *Syslog) readStream(conn net.Conn) {
+	defer s.wg.Done()
+	defer s.Untrack(conn)
+
+	r := bufio.NewReader(conn)
+	for {
+		if s.ReadTimeout.Duration > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.ReadTimeout.Duration))
+		}
+		buf, err := readFrame(r, s.Framing)
+		if len(buf) > 0 {
+			s.add(buf)
+		}
+		if err != nil {
+			if err != io.EOF && !s.Stopping() {
+				s.log.Errorf("Error reading from %s: %s", conn.RemoteAddr(), err)
+			}
+			return
+		}
+	}
+}
+
+// add parses a message into a point, to be added on the next Gather
+func (s *Syslog) add(buf []byte) {
+	if len(strings.TrimSpace(string(buf))) == 0 {
+		return
+	}
+	m, err := parseMessage(buf)
+	if err != nil {
+		s.log.Errorf("Could not parse message: %q, error: %s", buf, err)
+		return
+	}
+
+	tags := map[string]string{
+		"facility": facilities[m.facility],
+		"severity": severities[m.severity],
+	}
+	if m.hostname != "" {
+		tags["hostname"] = m.hostname
+	}
+	if m.appname != "" {
+		tags["appname"] = m.appname
+	}
+
+	fields := map[string]interface{}{
+		"facility_code": m.facility,
+		"severity_code": m.severity,
+		"message":       m.message,
+	}
+	if m.version != 0 {
+		fields["version"] = m.version
+	}
+	if !m.timestamp.IsZero() {
+		fields["timestamp"] = m.timestamp.UnixNano()
+	}
+	if m.procid != "" {
+		fields["procid"] = m.procid
+	}
+	if m.msgid != "" {
+		fields["msgid"] = m.msgid
+	}
+	for id, params := range m.structured {
+		for name, value := range params {
+			fields[id+"_"+name] = value
+		}
+	}
+
+	pt, err := models.NewPoint("syslog", tags, fields, time.Now())
+	if err != nil {
+		s.log.Errorf("Could not create point from message: %q, error: %s",
+			buf, err)
+		return
+	}
+	s.buffer.Add(pt)
+}
+
+func (s *Syslog) Stop() {
+	s.Close()
+	s.wg.Wait()
+}
+
+// Gather adds the messages received since the last Gather
+func (s *Syslog) Gather(acc plugins.Accumulator) error {
+	s.buffer.Gather(acc, s.log)
+	return nil
+}
+
+func init() {
+	plugins.Add("syslog", func() plugins.Plugin {
+		return NewSyslog()
+	})
+}
@@ -0,0 +1,121 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/influxdb/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func octetCounted(msg string) string {
+	return fmt.Sprintf("%d %s", len(msg), msg)
+}
+
+func TestSyslogUDP(t *testing.T) {
+	s := NewSyslog()
+	s.Address = "udp://127.0.0.1:0"
+	defer testutil.StartService(t, s)()
+
+	conn, err := net.Dial("udp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte(`<165>1 2003-10-11T22:14:15.003Z router1 evntslog 42 ID47 ` +
+		`[origin ip="10.0.0.1"] link down`))
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, s, &acc, 1)
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("syslog",
+		map[string]interface{}{
+			"facility_code": int64(20),
+			"severity_code": int64(5),
+			"version":       int64(1),
+			"timestamp":     int64(1065910455003000000),
+			"procid":        "42",
+			"msgid":         "ID47",
+			"origin_ip":     "10.0.0.1",
+			"message":       "link down",
+		},
+		map[string]string{
+			"facility": "local4",
+			"severity": "notice",
+			"hostname": "router1",
+			"appname":  "evntslog",
+		}))
+}
+
+func TestSyslogTCP(t *testing.T) {
+	s := NewSyslog()
+	s.Address = "tcp://127.0.0.1:0"
+	defer testutil.StartService(t, s)()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	// octet counting and non-transparent framing on the same connection
+	_, err = conn.Write([]byte(octetCounted("<13>1 - host1 app - - -") +
+		"<13>Oct 11 22:14:15 host2 cron: job done\n"))
+	require.NoError(t, err)
+	conn.Close()
+
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, s, &acc, 2)
+	assert.Equal(t, "host1", acc.Points[0].Tags["hostname"])
+	assert.Equal(t, "host2", acc.Points[1].Tags["hostname"])
+	assert.Equal(t, "cron", acc.Points[1].Tags["appname"])
+	assert.Equal(t, "job done", acc.Points[1].Fields["message"])
+}
+
+func TestSyslogTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syslog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	files, err := testutil.NewTLSFiles(dir)
+	require.NoError(t, err)
+
+	s := NewSyslog()
+	s.Address = "tls://127.0.0.1:0"
+	s.Framing = "octet-counting"
+	s.TLSCert = files.Cert
+	s.TLSKey = files.Key
+	s.TLSAllowedCACerts = []string{files.Cert}
+	defer testutil.StartService(t, s)()
+
+	conn, err := tls.Dial("tcp", s.Addr().String(), files.ClientConfig)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte(octetCounted("<13>1 - host1 app - - - hi")))
+	require.NoError(t, err)
+	conn.Close()
+
+	var acc testutil.Accumulator
+	testutil.GatherUntil(t, s, &acc, 1)
+	assert.Equal(t, "hi", acc.Points[0].Fields["message"])
+
+	// clients without a certificate are refused
+	conn, err = tls.Dial("tcp", s.Addr().String(),
+		&tls.Config{RootCAs: files.ClientConfig.RootCAs})
+	if err == nil {
+		_, err = conn.Write([]byte(octetCounted("<13>1 - host1 app - - - hi")))
+		if err == nil {
+			_, err = conn.Read(make([]byte, 1))
+		}
+		conn.Close()
+	}
+	assert.Error(t, err)
+}
+
+func TestSyslogBadConfig(t *testing.T) {
+	for _, s := range []*Syslog{
+		{Address: "udp://127.0.0.1:0", Framing: "lines"},
+		{Address: "http://127.0.0.1:0", Framing: "auto"},
+		{Address: "tls://127.0.0.1:0", Framing: "auto"},
+	} {
+		assert.Error(t, s.Start(), s.Address)
+	}
+}
@@ -23,7 +23,7 @@ var netSampleConfig = `
   # Setting interfaces will tell it to gather these explicit interfaces,
   # regardless of status.
   #
-  # interfaces = ["eth0", ... ]
+  # interfaces = ["eth0"]
 `
 
 func (_ *NetIOStats) SampleConfig() string {
@@ -0,0 +1,488 @@
+package tail
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/internal/parsers"
+	"github.com/influxdb/telegraf/internal/service"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+const sampleConfig = `
+  # files to follow, as glob patterns that are expanded on every poll
+  files = ["/var/log/myapp/*.log"]
+
+  # read files that exist at startup from the beginning, rather than only
+  # the lines written after startup. Files that appear later, such as the new
+  # file of a logrotate, are always read from the beginning.
+  from_beginning = false
+
+  # file the read offsets are kept in, so that a restart resumes where it
+  # stopped instead of skipping or duplicating lines
+  # state_file = "/var/lib/telegraf/tail.state"
+
+  # how often files are checked for new lines, rotation and truncation
+  poll_interval = "1s"
+
+  # format of the lines, "influx" or "json"
+  data_format = "influx"
+  # measurement name and tag keys of json lines
+  # metric_name = "myapp"
+  # tag_keys = ["level"]
+
+  # maximum number of points to buffer between collection intervals. Once it
+  # is reached, reading pauses until the points are gathered.
+  point_buffer = 100000
+`
+
+// maxLineSize bounds the bytes kept of a line that has no newline yet
+const maxLineSize = 1 << 20
+
+type Tail struct {
+	Files         []string          `doc:"glob patterns of the files to follow"`
+	FromBeginning bool              `doc:"read files that exist at startup from the beginning"`
+	StateFile     string            `doc:"file the read offsets are kept in across restarts"`
+	PollInterval  internal.Duration `doc:"how often files are checked for new lines"`
+	DataFormat    string            `doc:"format of the lines: influx or json"`
+	MetricName    string            `doc:"measurement name of json lines"`
+	TagKeys       []string          `doc:"json keys to use as tags"`
+	PointBuffer   int               `doc:"maximum number of points to buffer between collection intervals"`
+
+	sync.Mutex
+	parser  parsers.Parser
+	tailers map[string]*tailer
+	state   map[string]fileState
+	buffer  service.Buffer
+	// paused is set once reading paused because the buffer is full, until
+	// the next Gather
+	paused  bool
+	done    chan struct{}
+	stopped chan struct{}
+
+	log *logger.Logger
+}
+
+// tailer follows a single path. It keeps reading the file it has open
+// until the path is found to point to another file, so that lines written
+// to a file right before it is renamed by logrotate aren't lost.
+type tailer struct {
+	path    string
+	file    *os.File
+	info    os.FileInfo
+	offset  int64
+	partial []byte
+}
+
+// fileState is the read offset of a path, saved in the state file. ID
+// identifies the file that the offset is in, where the platform supports it,
+// otherwise the size of the file is used to tell whether it was truncated.
+type fileState struct {
+	ID     uint64 `json:"id"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+}
+
+func newFileState(info os.FileInfo, offset int64) fileState {
+	return fileState{
+		ID:     fileID(info),
+		Offset: offset,
+		Size:   info.Size(),
+	}
+}
+
+// resumable returns true if the saved offset can be used for the file with
+// the given info and ID. Without an ID, the file is resumed as long as it
+// hasn't shrunk since the offset was saved, which would mean it was
+// truncated.
+func (s fileState) resumable(info os.FileInfo, id uint64) bool {
+	if s.Offset > info.Size() {
+		return false
+	}
+	if id != 0 {
+		return s.ID == id
+	}
+	return info.Size() >= s.Size
+}
+
+func NewTail() *Tail {
+	return &Tail{
+		PollInterval: internal.Duration{Duration: time.Second},
+		MetricName:   "tail",
+		PointBuffer:  service.DefaultPointBuffer,
+	}
+}
+
+func (t *Tail) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *Tail) Description() string {
+	return "Follow log files and parse their lines into metrics"
+}
+
+func (t *Tail) SetLogger(log *logger.Logger) {
+	t.log = log
+}
+
+// SetParser sets the parser lines are parsed with, instead of the parser of
+// data_format. It is used by plugins that follow files in their own format,
+// such as logparser.
+func (t *Tail) SetParser(parser parsers.Parser) {
+	t.parser = parser
+}
+
+func (t *Tail) Start() error {
+	if len(t.Files) == 0 {
+		return fmt.Errorf("tail: no files to follow")
+	}
+	if t.PollInterval.Duration <= 0 {
+		return fmt.Errorf("tail: poll_interval must be positive")
+	}
+
+	var err error
+	if t.parser == nil {
+		t.parser, err = parsers.NewParser(&parsers.Config{
+			DataFormat: t.DataFormat,
+			MetricName: t.MetricName,
+			TagKeys:    t.TagKeys,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	t.buffer.SetMax(t.PointBuffer)
+	t.tailers = make(map[string]*tailer)
+	t.state, err = loadState(t.StateFile)
+	if err != nil {
+		return err
+	}
+
+	t.Lock()
+	t.poll(true)
+	t.Unlock()
+
+	t.done = make(chan struct{})
+	t.stopped = make(chan struct{})
+	go t.follow()
+	return nil
+}
+
+// follow polls the files until the plugin is stopped
+func (t *Tail) follow() {
+	defer close(t.stopped)
+
+	ticker := time.NewTicker(t.PollInterval.Duration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			t.Lock()
+			t.poll(false)
+			t.Unlock()
+		}
+	}
+}
+
+// poll opens the files that match the globs, and reads the lines written to
+// the open files since the last poll. On the first poll, files are read from
+// their saved offset, or from the beginning or end depending on
+// from_beginning.
+func (t *Tail) poll(first bool) {
+	paths := make(map[string]bool)
+	for _, pattern := range t.Files {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			t.log.Errorf("Invalid glob %q: %s", pattern, err)
+			continue
+		}
+		for _, path := range matches {
+			paths[path] = true
+		}
+	}
+	for path := range t.tailers {
+		paths[path] = true
+	}
+
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	for _, path := range sorted {
+		tl, ok := t.tailers[path]
+		if !ok {
+			tl = &tailer{path: path}
+			if err := t.open(tl, first); err != nil {
+				t.log.Errorf("Unable to open %s: %s", path, err)
+				continue
+			}
+			t.tailers[path] = tl
+		}
+
+		// a paused file is checked for rotation and truncation once it is
+		// read to the end, so that its remaining lines aren't lost
+		if !t.read(tl) {
+			continue
+		}
+		if !t.reopen(tl) {
+			delete(t.tailers, path)
+			delete(t.state, path)
+		}
+	}
+}
+
+// open opens a path that isn't followed yet, and seeks to where reading
+// should start.
+func (t *Tail) open(tl *tailer, first bool) error {
+	file, err := os.Open(tl.path)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	offset := int64(0)
+	if s, ok := t.state[tl.path]; ok {
+		if s.resumable(info, fileID(info)) {
+			offset = s.Offset
+		}
+	} else if first && !t.FromBeginning {
+		offset = info.Size()
+	}
+	if _, err := file.Seek(offset, 0); err != nil {
+		file.Close()
+		return err
+	}
+
+	tl.file, tl.info, tl.offset, tl.partial = file, info, offset, nil
+	t.state[tl.path] = newFileState(info, offset)
+	return nil
+}
+
+// reopen checks whether the path of a tailer still points to the file it
+// has open. A file that was rotated by a rename is replaced with the new
+// file at the path, and a file that was truncated is read again from the
+// beginning. The last line of a file that is replaced is parsed even if it
+// has no newline. It returns false if the path no longer exists.
+func (t *Tail) reopen(tl *tailer) bool {
+	info, err := os.Stat(tl.path)
+	if err != nil {
+		if !t.flush(tl) {
+			return true
+		}
+		tl.file.Close()
+		return false
+	}
+
+	if !os.SameFile(info, tl.info) {
+		// lines written right before the rename are read from the old file
+		if !t.read(tl) || !t.flush(tl) {
+			return true
+		}
+		t.log.Infof("%s was rotated, reopening", tl.path)
+		tl.file.Close()
+		if err := t.open(tl, false); err != nil {
+			t.log.Errorf("Unable to open %s: %s", tl.path, err)
+			return false
+		}
+		t.read(tl)
+		return true
+	}
+
+	if info.Size() < tl.offset+int64(len(tl.partial)) {
+		if !t.flush(tl) {
+			return true
+		}
+		t.log.Infof("%s was truncated, reading from the beginning", tl.path)
+		if _, err := tl.file.Seek(0, 0); err != nil {
+			t.log.Errorf("Unable to seek %s: %s", tl.path, err)
+			tl.file.Close()
+			return false
+		}
+		tl.offset, tl.partial = 0, nil
+		tl.info = info
+		t.read(tl)
+	}
+	tl.info = info
+	t.state[tl.path] = newFileState(info, tl.offset)
+	return true
+}
+
+// read parses the complete lines written to the open file of a tailer.
+// Once the buffer is full, it stops at the line that didn't fit, to read it
+// again after the next Gather, and returns false.
+func (t *Tail) read(tl *tailer) bool {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := tl.file.Read(buf)
+		data := append(tl.partial, buf[:n]...)
+		for {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				break
+			}
+			if !t.parse(tl.path, data[:i]) {
+				t.pause(tl)
+				return false
+			}
+			tl.offset += int64(i + 1)
+			data = data[i+1:]
+		}
+
+		if len(data) > maxLineSize {
+			t.log.Errorf("Line of %s is longer than %d bytes, skipping it",
+				tl.path, maxLineSize)
+			tl.offset += int64(len(data))
+			data = nil
+		}
+		tl.partial = append([]byte(nil), data...)
+
+		if err == io.EOF || n == 0 {
+			break
+		}
+		if err != nil {
+			t.log.Errorf("Error reading %s: %s", tl.path, err)
+			break
+		}
+	}
+	t.state[tl.path] = newFileState(tl.info, tl.offset)
+	return true
+}
+
+// flush parses the unterminated last line of a file that is being replaced.
+// It returns false if it doesn't fit in the buffer, in which case the line is
+// kept for the next poll.
+func (t *Tail) flush(tl *tailer) bool {
+	if len(tl.partial) == 0 {
+		return true
+	}
+	if !t.parse(tl.path, tl.partial) {
+		t.warnFull()
+		return false
+	}
+	tl.offset += int64(len(tl.partial))
+	tl.partial = nil
+	return true
+}
+
+// pause rewinds a tailer to the line that didn't fit in the buffer
+func (t *Tail) pause(tl *tailer) {
+	t.warnFull()
+	if _, err := tl.file.Seek(tl.offset, 0); err != nil {
+		t.log.Errorf("Unable to seek %s: %s", tl.path, err)
+	}
+	tl.partial = nil
+	t.state[tl.path] = newFileState(tl.info, tl.offset)
+}
+
+// warnFull warns that reading paused, once per Gather
+func (t *Tail) warnFull() {
+	if !t.paused {
+		t.log.Warnf("Buffer is full, pausing reading until the next Gather, " +
+			"you may want to increase point_buffer")
+		t.paused = true
+	}
+}
+
+// parse parses a line into points, to be added on the next Gather. It
+// returns false if they don't fit in the buffer.
+func (t *Tail) parse(path string, line []byte) bool {
+	line = bytes.TrimRight(line, "\r")
+	if len(bytes.TrimSpace(line)) == 0 {
+		return true
+	}
+	points, err := t.parser.Parse(line)
+	if err != nil {
+		t.log.Errorf("Could not parse line of %s: %s, error: %s",
+			path, line, err)
+		return true
+	}
+	return t.buffer.TryAdd(points...)
+}
+
+func loadState(path string) (map[string]fileState, error) {
+	state := make(map[string]fileState)
+	if path == "" {
+		return state, nil
+	}
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(buf, &state); err != nil {
+		return nil, fmt.Errorf("tail: invalid state file %s: %s", path, err)
+	}
+	return state, nil
+}
+
+// saveState writes the state file through a temporary file, so that a crash
+// doesn't leave it half written
+func saveState(path string, state map[string]fileState) error {
+	if path == "" {
+		return nil
+	}
+	buf, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (t *Tail) Stop() {
+	close(t.done)
+	<-t.stopped
+
+	t.Lock()
+	defer t.Unlock()
+	for _, tl := range t.tailers {
+		tl.file.Close()
+	}
+	t.tailers = nil
+}
+
+// Gather adds the metrics parsed from the lines read since the last Gather,
+// then saves the read offsets, so that lines whose metrics weren't gathered
+// are read again after a restart.
+func (t *Tail) Gather(acc plugins.Accumulator) error {
+	t.Lock()
+	defer t.Unlock()
+
+	t.buffer.Gather(acc, t.log)
+	t.paused = false
+
+	if err := saveState(t.StateFile, t.state); err != nil {
+		t.log.Errorf("Unable to save state to %s: %s", t.StateFile, err)
+	}
+	return nil
+}
+
+func init() {
+	plugins.Add("tail", func() plugins.Plugin {
+		return NewTail()
+	})
+}
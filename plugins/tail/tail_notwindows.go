@@ -0,0 +1,17 @@
+// +build !windows
+
+package tail
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID identifies a file by its device and inode, so that a saved offset
+// isn't applied to another file at the same path after a restart
+func fileID(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev)<<32 ^ uint64(st.Ino)
+	}
+	return 0
+}
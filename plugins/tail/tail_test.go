@@ -0,0 +1,318 @@
+package tail
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTail returns a Tail that only polls when the test calls poll
+func newTail(files ...string) *Tail {
+	t := NewTail()
+	t.Files = files
+	t.FromBeginning = true
+	t.PollInterval = internal.Duration{Duration: time.Hour}
+	return t
+}
+
+func poll(t *Tail) {
+	t.Lock()
+	t.poll(false)
+	t.Unlock()
+}
+
+func appendFile(t *testing.T, path string, data string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(data)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+// depths returns the depth field of the gathered points, in order
+func depths(t *Tail) []int64 {
+	var acc testutil.Accumulator
+	t.Gather(&acc)
+	var values []int64
+	for _, p := range acc.Points {
+		values = append(values, p.Fields["depth"].(int64))
+	}
+	return values
+}
+
+func TestTailLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "jobs,queue=a depth=1i\n")
+
+	tl := newTail(filepath.Join(dir, "*.log"))
+	require.NoError(t, tl.Start())
+	defer tl.Stop()
+	assert.Equal(t, []int64{1}, depths(tl))
+
+	// a line is only parsed once it is complete
+	appendFile(t, path, "jobs,queue=a depth=2i\njobs,queue=a dep")
+	poll(tl)
+	assert.Equal(t, []int64{2}, depths(tl))
+	appendFile(t, path, "th=3i\n")
+	poll(tl)
+
+	var acc testutil.Accumulator
+	require.NoError(t, tl.Gather(&acc))
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("jobs",
+		map[string]interface{}{"depth": int64(3)},
+		map[string]string{"queue": "a"}))
+
+	// files that appear later are read from the beginning
+	appendFile(t, filepath.Join(dir, "other.log"), "jobs depth=4i\n")
+	poll(tl)
+	assert.Equal(t, []int64{4}, depths(tl))
+}
+
+func TestTailFromEnd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "jobs depth=1i\n")
+
+	tl := newTail(path)
+	tl.FromBeginning = false
+	require.NoError(t, tl.Start())
+	defer tl.Stop()
+	assert.Empty(t, depths(tl))
+
+	appendFile(t, path, "jobs depth=2i\n")
+	poll(tl)
+	assert.Equal(t, []int64{2}, depths(tl))
+}
+
+func TestTailRename(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "jobs depth=1i\n")
+
+	tl := newTail(path)
+	require.NoError(t, tl.Start())
+	defer tl.Stop()
+	assert.Equal(t, []int64{1}, depths(tl))
+
+	// lines written right before the rename are read from the old file
+	appendFile(t, path, "jobs depth=2i\n")
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendFile(t, path, "jobs depth=3i\n")
+	poll(tl)
+	assert.Equal(t, []int64{2, 3}, depths(tl))
+
+	appendFile(t, path+".1", "jobs depth=99i\n")
+	appendFile(t, path, "jobs depth=4i\n")
+	poll(tl)
+	assert.Equal(t, []int64{4}, depths(tl))
+}
+
+func TestTailRenamePartialLine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "jobs depth=1i\njobs dep")
+
+	tl := newTail(path)
+	require.NoError(t, tl.Start())
+	defer tl.Stop()
+	assert.Equal(t, []int64{1}, depths(tl))
+
+	// the last line of the old file is parsed even though it has no newline
+	appendFile(t, path, "th=2i")
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendFile(t, path, "jobs depth=3i\n")
+	poll(tl)
+	assert.Equal(t, []int64{2, 3}, depths(tl))
+}
+
+func TestTailBufferFull(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "jobs depth=1i\njobs depth=2i\njobs depth=3i\n")
+
+	tl := newTail(path)
+	tl.PointBuffer = 2
+	require.NoError(t, tl.Start())
+	defer tl.Stop()
+
+	// reading pauses at the line that doesn't fit, and resumes there
+	assert.Equal(t, []int64{1, 2}, depths(tl))
+	poll(tl)
+	assert.Equal(t, []int64{3}, depths(tl))
+
+	// a paused file is read to the end before it is replaced
+	appendFile(t, path, "jobs depth=4i\njobs depth=5i\njobs depth=6i\n")
+	poll(tl)
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendFile(t, path, "jobs depth=7i\n")
+	poll(tl)
+	assert.Equal(t, []int64{4, 5}, depths(tl))
+	poll(tl)
+	assert.Equal(t, []int64{6, 7}, depths(tl))
+}
+
+func TestTailTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "jobs depth=1i\njobs depth=2i\n")
+
+	tl := newTail(path)
+	require.NoError(t, tl.Start())
+	defer tl.Stop()
+	assert.Equal(t, []int64{1, 2}, depths(tl))
+
+	// copytruncate
+	require.NoError(t, os.Truncate(path, 0))
+	appendFile(t, path, "jobs depth=3i\n")
+	poll(tl)
+	assert.Equal(t, []int64{3}, depths(tl))
+
+	// a line without a newline before the truncation is parsed too
+	appendFile(t, path, "jobs depth=4i")
+	poll(tl)
+	require.NoError(t, os.Truncate(path, 0))
+	appendFile(t, path, "jobs depth=5i\n")
+	poll(tl)
+	assert.Equal(t, []int64{4, 5}, depths(tl))
+}
+
+func TestTailStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	state := filepath.Join(dir, "tail.state")
+	appendFile(t, path, "jobs depth=1i\n")
+
+	tl := newTail(path)
+	tl.StateFile = state
+	require.NoError(t, tl.Start())
+	assert.Equal(t, []int64{1}, depths(tl))
+	tl.Stop()
+
+	// a restart resumes after the lines that were read
+	appendFile(t, path, "jobs depth=2i\n")
+	tl = newTail(path)
+	tl.StateFile = state
+	require.NoError(t, tl.Start())
+	assert.Equal(t, []int64{2}, depths(tl))
+	tl.Stop()
+
+	// unless the file was replaced in the meantime
+	appendFile(t, path+".new", "jobs depth=3i\njobs depth=4i\n")
+	require.NoError(t, os.Rename(path+".new", path))
+	tl = newTail(path)
+	tl.StateFile = state
+	tl.FromBeginning = false
+	require.NoError(t, tl.Start())
+	defer tl.Stop()
+	assert.Equal(t, []int64{3, 4}, depths(tl))
+}
+
+func TestTailStateSavedOnGather(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	state := filepath.Join(dir, "tail.state")
+	appendFile(t, path, "jobs depth=1i\n")
+
+	tl := newTail(path)
+	tl.StateFile = state
+	require.NoError(t, tl.Start())
+	assert.Equal(t, []int64{1}, depths(tl))
+
+	// lines read but not gathered before a restart are read again
+	appendFile(t, path, "jobs depth=2i\n")
+	poll(tl)
+	tl.Stop()
+
+	tl = newTail(path)
+	tl.StateFile = state
+	require.NoError(t, tl.Start())
+	defer tl.Stop()
+	assert.Equal(t, []int64{2}, depths(tl))
+}
+
+func TestFileStateResumable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "jobs depth=1i\n")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	s := newFileState(info, info.Size())
+	assert.True(t, s.resumable(info, fileID(info)))
+	assert.True(t, s.resumable(info, 0))
+
+	// without a file ID, a file that was appended to is resumed
+	appendFile(t, path, "jobs depth=2i\n")
+	changed, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, s.resumable(changed, 0))
+
+	// but not one that was truncated, even if it has grown past the offset
+	// again
+	require.NoError(t, os.Truncate(path, 0))
+	appendFile(t, path, "jobs depth=3i\n")
+	truncated, err := os.Stat(path)
+	require.NoError(t, err)
+	s = newFileState(changed, info.Size())
+	assert.False(t, s.resumable(truncated, 0))
+
+	s.Offset = changed.Size() + 1
+	assert.False(t, s.resumable(changed, fileID(changed)))
+	assert.False(t, s.resumable(changed, 0))
+}
+
+func TestTailJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, `{"level": "error", "depth": 5}`+"\n")
+
+	tl := newTail(path)
+	tl.DataFormat = "json"
+	tl.MetricName = "app"
+	tl.TagKeys = []string{"level"}
+	require.NoError(t, tl.Start())
+	defer tl.Stop()
+
+	var acc testutil.Accumulator
+	require.NoError(t, tl.Gather(&acc))
+	assert.NoError(t, acc.ValidateTaggedFieldsValue("app",
+		map[string]interface{}{"depth": float64(5)},
+		map[string]string{"level": "error"}))
+}
+
+func TestTailBadConfig(t *testing.T) {
+	assert.Error(t, NewTail().Start())
+
+	tl := newTail("/tmp/*.log")
+	tl.DataFormat = "xml"
+	assert.Error(t, tl.Start())
+}
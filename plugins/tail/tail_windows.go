@@ -0,0 +1,11 @@
+// +build windows
+
+package tail
+
+import "os"
+
+// fileID isn't supported on windows, a saved offset is used as long as the
+// file at its path hasn't shrunk
+func fileID(info os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,172 @@
+package telegraf
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdb/telegraf/internal/config"
+	"github.com/influxdb/telegraf/internal/logger"
+	"github.com/influxdb/telegraf/internal/secret"
+)
+
+type pluginStatus struct {
+	Name       string     `json:"name"`
+	Alias      string     `json:"alias,omitempty"`
+	LastGather *time.Time `json:"last_gather,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+type outputStatus struct {
+	Name         string     `json:"name"`
+	Alias        string     `json:"alias,omitempty"`
+	LastWrite    *time.Time `json:"last_write,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	FailedWrites int        `json:"failed_writes"`
+	Healthy      bool       `json:"healthy"`
+
+	fullName string
+}
+
+// bufferStatus counts the points of an output that are waiting to be
+// written, and the points it dropped since startup
+type bufferStatus struct {
+	Buffered int `json:"buffered"`
+	Dropped  int `json:"dropped"`
+}
+
+type agentStatus struct {
+	Version  string          `json:"version"`
+	Hostname string          `json:"hostname"`
+	Healthy  bool            `json:"healthy"`
+	Pending  int             `json:"pending"`
+	Plugins  []*pluginStatus `json:"plugins"`
+	Outputs  []*outputStatus `json:"outputs"`
+	// Buffers are keyed by the full name of their output
+	Buffers map[string]*bufferStatus `json:"buffers"`
+}
+
+// statusTimeout bounds the time taken to read a status request and to write
+// its response
+const statusTimeout = 10 * time.Second
+
+// serveStatus starts an HTTP listener on the configured status address, it
+// is closed on shutdown.
+func (a *Agent) serveStatus(shutdown chan struct{}) error {
+	listener, err := net.Listen("tcp", a.Config.Agent.StatusAddress)
+	if err != nil {
+		return fmt.Errorf("Status listener failed to start: %s", err)
+	}
+
+	server := &http.Server{
+		Handler:      a.statusHandler(),
+		ReadTimeout:  statusTimeout,
+		WriteTimeout: statusTimeout,
+	}
+	go server.Serve(listener)
+	go func() {
+		<-shutdown
+		listener.Close()
+	}()
+
+	logger.Infof("Serving /health and /status on %s", listener.Addr())
+	return nil
+}
+
+func (a *Agent) statusHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", a.serveHealth)
+	mux.HandleFunc("/status", a.serveStatusJSON)
+	return mux
+}
+
+// serveHealth responds with 200 unless an output has failed its last
+// health_max_failed_writes writes, in which case it responds with 503 and
+// lists the failing outputs.
+func (a *Agent) serveHealth(w http.ResponseWriter, r *http.Request) {
+	status := a.status()
+	if status.Healthy {
+		fmt.Fprintln(w, "OK")
+		return
+	}
+
+	w.WriteHeader(http.StatusServiceUnavailable)
+	for _, o := range status.Outputs {
+		if !o.Healthy {
+			fmt.Fprintf(w, "output %s failed its last %d writes: %s\n",
+				o.fullName, o.FailedWrites, o.LastError)
+		}
+	}
+}
+
+func (a *Agent) serveStatusJSON(w http.ResponseWriter, r *http.Request) {
+	status := a.status()
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(status)
+}
+
+// status takes a snapshot of the plugin and output state tracked by the
+// agent.
+func (a *Agent) status() *agentStatus {
+	status := &agentStatus{
+		Version:  a.Version,
+		Hostname: a.Config.Agent.Hostname,
+		Healthy:  true,
+		Pending:  int(atomic.LoadInt32(&a.pending)),
+		Plugins:  make([]*pluginStatus, 0, len(a.Config.Plugins)),
+		Outputs:  make([]*outputStatus, 0, len(a.Config.Outputs)),
+		Buffers:  make(map[string]*bufferStatus),
+	}
+
+	for _, rp := range a.Config.Plugins {
+		ps := &pluginStatus{Name: rp.Name, Alias: rp.Alias}
+		lastGather, err := rp.GatherResult()
+		if !lastGather.IsZero() {
+			ps.LastGather = &lastGather
+		}
+		if err != nil {
+			ps.LastError = secret.Redact(err.Error())
+		}
+		status.Plugins = append(status.Plugins, ps)
+	}
+
+	var outputs []*config.RunningOutput
+	for _, ro := range a.Config.Outputs {
+		outputs = append(outputs, ro)
+		outputs = append(outputs, ro.Routes()...)
+	}
+
+	maxFailed := a.Config.Agent.HealthMaxFailedWrites
+	for _, ro := range outputs {
+		s := ro.Status()
+		ostatus := &outputStatus{
+			Name:         ro.Name,
+			Alias:        ro.Alias,
+			FailedWrites: s.FailedWrites,
+			Healthy:      maxFailed <= 0 || s.FailedWrites < maxFailed,
+			fullName:     ro.FullName(),
+		}
+		if !s.LastWrite.IsZero() {
+			ostatus.LastWrite = &s.LastWrite
+		}
+		if s.LastErr != nil {
+			ostatus.LastError = secret.Redact(s.LastErr.Error())
+		}
+		if !ostatus.Healthy {
+			status.Healthy = false
+		}
+		status.Outputs = append(status.Outputs, ostatus)
+		status.Buffers[ro.FullName()] = &bufferStatus{
+			Buffered: s.Buffered,
+			Dropped:  s.Dropped,
+		}
+	}
+
+	return status
+}
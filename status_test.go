@@ -0,0 +1,100 @@
+package telegraf
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatus_Healthy(t *testing.T) {
+	a := newOnceAgent(&onceOutput{})
+	a.Version = "0.2.4"
+	require.NoError(t, a.Once())
+
+	rec := httptest.NewRecorder()
+	a.statusHandler().ServeHTTP(rec, newRequest(t, "/health"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	a.statusHandler().ServeHTTP(rec, newRequest(t, "/status"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var status agentStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, "0.2.4", status.Version)
+	assert.True(t, status.Healthy)
+	assert.Equal(t, 0, status.Pending)
+	require.Len(t, status.Plugins, 1)
+	assert.Equal(t, "trig", status.Plugins[0].Name)
+	assert.NotNil(t, status.Plugins[0].LastGather)
+	assert.Empty(t, status.Plugins[0].LastError)
+	require.Len(t, status.Outputs, 1)
+	assert.NotNil(t, status.Outputs[0].LastWrite)
+	assert.Equal(t, &bufferStatus{}, status.Buffers["once"])
+}
+
+func TestStatus_UnhealthyOutput(t *testing.T) {
+	a := newOnceAgent(&onceOutput{err: errors.New("connection refused")})
+	a.Config.Agent.HealthMaxFailedWrites = 1
+	a.Config.Outputs[0].Alias = "tenant_a"
+	assert.Error(t, a.Once())
+
+	rec := httptest.NewRecorder()
+	a.statusHandler().ServeHTTP(rec, newRequest(t, "/health"))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "once::tenant_a")
+	assert.Contains(t, rec.Body.String(), "connection refused")
+
+	rec = httptest.NewRecorder()
+	a.statusHandler().ServeHTTP(rec, newRequest(t, "/status"))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var status agentStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.False(t, status.Healthy)
+	assert.Equal(t, 1, status.Outputs[0].FailedWrites)
+	assert.Equal(t, "connection refused", status.Outputs[0].LastError)
+	assert.Equal(t, &bufferStatus{Dropped: 1}, status.Buffers["once::tenant_a"])
+}
+
+// blockingOutput blocks its writes until unblock is closed
+type blockingOutput struct {
+	onceOutput
+	writing chan struct{}
+	unblock chan struct{}
+}
+
+func (o *blockingOutput) Write(points []*client.Point) error {
+	close(o.writing)
+	<-o.unblock
+	return nil
+}
+
+func TestStatus_Buffered(t *testing.T) {
+	output := &blockingOutput{
+		writing: make(chan struct{}),
+		unblock: make(chan struct{}),
+	}
+	a := newOnceAgent(nil)
+	a.Config.Outputs[0].Output = output
+	done := make(chan error)
+	go func() { done <- a.Once() }()
+
+	<-output.writing
+	assert.Equal(t, &bufferStatus{Buffered: 1}, a.status().Buffers["once"])
+	close(output.unblock)
+	require.NoError(t, <-done)
+	assert.Equal(t, &bufferStatus{}, a.status().Buffers["once"])
+}
+
+func newRequest(t *testing.T, path string) *http.Request {
+	req, err := http.NewRequest("GET", path, nil)
+	require.NoError(t, err)
+	return req
+}
@@ -5,6 +5,8 @@ import (
 	"reflect"
 	"sync"
 	"time"
+
+	"github.com/influxdb/telegraf/internal/logger"
 )
 
 // Point defines a single point measurement
@@ -95,6 +97,10 @@ func (a *Accumulator) SetDebug(debug bool) {
 	// stub for implementing Accumulator interface.
 }
 
+func (a *Accumulator) SetLogger(log *logger.Logger) {
+	// stub for implementing Accumulator interface.
+}
+
 // Get gets the specified measurement point from the accumulator
 func (a *Accumulator) Get(measurement string) (*Point, bool) {
 	for _, p := range a.Points {
@@ -0,0 +1,222 @@
+package testutil
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+)
+
+// MockClock is a deterministic internal.Clock, its time only moves forward
+// when Add is called.
+//
+// Unlike a time.Ticker, a ticker of the MockClock doesn't drop ticks: Add
+// blocks until every tick that is due has been received, so that a loop
+// ticking on it runs once per interval.
+type MockClock struct {
+	mu       sync.Mutex
+	now      time.Time
+	tickers  []*mockTicker
+	sleepers []*sleeper
+	changed  chan struct{}
+}
+
+type sleeper struct {
+	until time.Time
+	done  chan struct{}
+}
+
+// NewMockClock returns a MockClock set to the given time
+func NewMockClock(now time.Time) *MockClock {
+	return &MockClock{now: now, changed: make(chan struct{})}
+}
+
+// Now returns the current time of the clock
+func (c *MockClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep blocks until the clock has been moved forward by d
+func (c *MockClock) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.mu.Lock()
+	s := &sleeper{until: c.now.Add(d), done: make(chan struct{})}
+	c.sleepers = append(c.sleepers, s)
+	c.notify()
+	c.mu.Unlock()
+	<-s.done
+}
+
+// After returns a channel that the clock time is sent on once the clock has
+// been moved forward by d
+func (c *MockClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.Now()
+		return ch
+	}
+	c.mu.Lock()
+	s := &sleeper{until: c.now.Add(d), done: make(chan struct{})}
+	c.sleepers = append(c.sleepers, s)
+	c.notify()
+	c.mu.Unlock()
+	go func() {
+		<-s.done
+		ch <- c.Now()
+	}()
+	return ch
+}
+
+// NewTicker returns a ticker that ticks every d of clock time
+func (c *MockClock) NewTicker(d time.Duration) internal.Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &mockTicker{
+		clock:  c,
+		period: d,
+		next:   c.now.Add(d),
+		c:      make(chan time.Time),
+		stop:   make(chan struct{}),
+	}
+	c.tickers = append(c.tickers, t)
+	c.notify()
+	return t
+}
+
+// Add moves the clock forward by d, waking up the sleepers and delivering
+// the ticks that are due, in order.
+func (c *MockClock) Add(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	c.mu.Unlock()
+
+	for {
+		c.mu.Lock()
+		next, ok := c.nextEvent(end)
+		if !ok {
+			c.now = end
+			c.wakeSleepers()
+			c.mu.Unlock()
+			return
+		}
+		c.now = next
+		c.wakeSleepers()
+		var due []*mockTicker
+		for _, t := range c.tickers {
+			if !t.next.After(next) {
+				t.next = t.next.Add(t.period)
+				due = append(due, t)
+			}
+		}
+		c.mu.Unlock()
+
+		for _, t := range due {
+			t.tick(next)
+		}
+	}
+}
+
+// WaitForTickers blocks until n tickers have been created and not stopped,
+// or the timeout expires. It returns false on timeout.
+func (c *MockClock) WaitForTickers(n int, timeout time.Duration) bool {
+	return c.waitFor(func() int { return len(c.tickers) }, n, timeout)
+}
+
+// WaitForSleepers blocks until n goroutines are sleeping or waiting on
+// After, or the timeout expires. It returns false on timeout.
+func (c *MockClock) WaitForSleepers(n int, timeout time.Duration) bool {
+	return c.waitFor(func() int { return len(c.sleepers) }, n, timeout)
+}
+
+func (c *MockClock) waitFor(count func() int, n int, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		c.mu.Lock()
+		count := count()
+		changed := c.changed
+		c.mu.Unlock()
+		if count >= n {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// nextEvent returns the time of the first tick or wake up that is due no
+// later than end.
+func (c *MockClock) nextEvent(end time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	for _, t := range c.tickers {
+		if !t.next.After(end) && (!found || t.next.Before(next)) {
+			next, found = t.next, true
+		}
+	}
+	for _, s := range c.sleepers {
+		if !s.until.After(end) && (!found || s.until.Before(next)) {
+			next, found = s.until, true
+		}
+	}
+	return next, found
+}
+
+func (c *MockClock) wakeSleepers() {
+	sleepers := c.sleepers[:0]
+	for _, s := range c.sleepers {
+		if s.until.After(c.now) {
+			sleepers = append(sleepers, s)
+		} else {
+			close(s.done)
+		}
+	}
+	c.sleepers = sleepers
+}
+
+func (c *MockClock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+type mockTicker struct {
+	clock  *MockClock
+	period time.Duration
+	next   time.Time
+	c      chan time.Time
+	stop   chan struct{}
+	once   sync.Once
+}
+
+func (t *mockTicker) C() <-chan time.Time { return t.c }
+
+// Stop removes the ticker from its clock, a tick being delivered to it is
+// dropped.
+func (t *mockTicker) Stop() {
+	t.once.Do(func() {
+		close(t.stop)
+		c := t.clock
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, other := range c.tickers {
+			if other == t {
+				c.tickers = append(c.tickers[:i], c.tickers[i+1:]...)
+				break
+			}
+		}
+		c.notify()
+	})
+}
+
+func (t *mockTicker) tick(now time.Time) {
+	select {
+	case t.c <- now:
+	case <-t.stop:
+	}
+}
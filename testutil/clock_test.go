@@ -0,0 +1,67 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockClockTicker(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := NewMockClock(start)
+	ticker := clock.NewTicker(10 * time.Second)
+
+	var ticks []time.Time
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for tick := range ticker.C() {
+			ticks = append(ticks, tick)
+			if len(ticks) == 3 {
+				return
+			}
+		}
+	}()
+
+	clock.Add(25 * time.Second)
+	clock.Add(5 * time.Second)
+	<-done
+	ticker.Stop()
+
+	assert.Equal(t, []time.Time{
+		start.Add(10 * time.Second),
+		start.Add(20 * time.Second),
+		start.Add(30 * time.Second),
+	}, ticks)
+	assert.Equal(t, start.Add(30*time.Second), clock.Now())
+}
+
+func TestMockClockSleep(t *testing.T) {
+	clock := NewMockClock(time.Unix(0, 0))
+	woken := make(chan struct{})
+	go func() {
+		clock.Sleep(time.Minute)
+		close(woken)
+	}()
+
+	for {
+		clock.mu.Lock()
+		n := len(clock.sleepers)
+		clock.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Add(59 * time.Second)
+	select {
+	case <-woken:
+		t.Fatal("Sleep returned before the clock moved by its duration")
+	default:
+	}
+
+	clock.Add(time.Second)
+	<-woken
+}
@@ -0,0 +1,131 @@
+package testutil
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+)
+
+// MockOutput is an output that records the points written to it. Writes can
+// be made to fail, or to take time.
+type MockOutput struct {
+	sync.Mutex
+
+	// Err is returned by every Write, or only by the first FailWrites
+	// writes if FailWrites is set
+	Err        error
+	FailWrites int
+	// Latency is how long each Write takes
+	Latency time.Duration
+
+	// Writes holds the points of every successful write
+	Writes [][]*client.Point
+	// Attempts counts every write, failed or not
+	Attempts int
+
+	Connected bool
+	Closed    bool
+
+	failed  int
+	written chan struct{}
+}
+
+func (o *MockOutput) Connect() error {
+	o.Lock()
+	defer o.Unlock()
+	o.Connected = true
+	return nil
+}
+
+func (o *MockOutput) Close() error {
+	o.Lock()
+	defer o.Unlock()
+	o.Closed = true
+	return nil
+}
+
+func (o *MockOutput) Description() string {
+	return "Mock output recording the points written to it"
+}
+
+func (o *MockOutput) SampleConfig() string {
+	return ""
+}
+
+// Write records points, unless it is made to fail by Err
+func (o *MockOutput) Write(points []*client.Point) error {
+	if o.Latency > 0 {
+		time.Sleep(o.Latency)
+	}
+
+	o.Lock()
+	defer o.Unlock()
+	o.Attempts++
+	if o.written != nil {
+		close(o.written)
+		o.written = nil
+	}
+
+	if o.Err != nil && (o.FailWrites == 0 || o.failed < o.FailWrites) {
+		o.failed++
+		return o.Err
+	}
+
+	o.Writes = append(o.Writes, points)
+	return nil
+}
+
+// Points returns every point that was written, in order
+func (o *MockOutput) Points() []*client.Point {
+	o.Lock()
+	defer o.Unlock()
+	var points []*client.Point
+	for _, w := range o.Writes {
+		points = append(points, w...)
+	}
+	return points
+}
+
+// WaitForPoints blocks until n points have been written, or the timeout
+// expires. It returns false on timeout.
+func (o *MockOutput) WaitForPoints(n int, timeout time.Duration) bool {
+	return o.waitFor(func() bool { return count(o.Writes) >= n }, timeout)
+}
+
+// WaitForAttempts blocks until n writes have been attempted, or the timeout
+// expires. It returns false on timeout.
+func (o *MockOutput) WaitForAttempts(n int, timeout time.Duration) bool {
+	return o.waitFor(func() bool { return o.Attempts >= n }, timeout)
+}
+
+// waitFor waits until cond, which is called with the output locked, is true
+func (o *MockOutput) waitFor(cond func() bool, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		o.Lock()
+		if cond() {
+			o.Unlock()
+			return true
+		}
+		if o.written == nil {
+			o.written = make(chan struct{})
+		}
+		written := o.written
+		o.Unlock()
+
+		select {
+		case <-written:
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+func count(writes [][]*client.Point) int {
+	n := 0
+	for _, w := range writes {
+		n += len(w)
+	}
+	return n
+}
@@ -0,0 +1,46 @@
+package testutil
+
+import (
+	"errors"
+	"time"
+)
+
+// RunTimeout bounds how long RunIntervals waits for the run function to
+// start and stop
+var RunTimeout = 5 * time.Second
+
+// RunIntervals starts run, typically Agent.Run, moves clock forward by
+// interval n times, and then shuts run down and waits for it to return.
+// The clock is first moved once tickers tickers have been created, ie two
+// for an agent with the default intervals: the gather and the flush tickers.
+// It returns the error of run.
+func RunIntervals(
+	clock *MockClock,
+	tickers int,
+	interval time.Duration,
+	n int,
+	run func(shutdown chan struct{}) error,
+) error {
+	shutdown := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- run(shutdown)
+	}()
+
+	if !clock.WaitForTickers(tickers, RunTimeout) {
+		close(shutdown)
+		return errors.New("timed out waiting for tickers to be created")
+	}
+
+	for i := 0; i < n; i++ {
+		clock.Add(interval)
+	}
+
+	close(shutdown)
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(RunTimeout):
+		return errors.New("timed out waiting for run to return")
+	}
+}
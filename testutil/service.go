@@ -0,0 +1,40 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/plugins"
+)
+
+// GatherTimeout bounds how long GatherUntil gathers
+var GatherTimeout = time.Second
+
+// StartService starts s, and fails the test if it can't. It returns s.Stop,
+// so that tests can defer StartService(t, s)().
+func StartService(t *testing.T, s plugins.ServicePlugin) func() {
+	if err := s.Start(); err != nil {
+		t.Fatalf("could not start: %s", err)
+	}
+	return s.Stop
+}
+
+// GatherUntil gathers from p into acc until acc has n points, and fails the
+// test if it still has fewer after GatherTimeout. It is meant for service
+// plugins, whose points arrive in the background.
+func GatherUntil(t *testing.T, p plugins.Plugin, acc *Accumulator, n int) {
+	deadline := time.Now().Add(GatherTimeout)
+	for {
+		p.Gather(acc)
+		acc.Lock()
+		count := len(acc.Points)
+		acc.Unlock()
+		if count >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d points, got %d", n, count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}